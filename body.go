@@ -0,0 +1,269 @@
+package oas
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/oasm"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	MimeFormUrlEncoded = "application/x-www-form-urlencoded"
+	MimeMultipartForm  = "multipart/form-data"
+	MimeTextPlain      = "text/plain"
+	MimeOctetStream    = "application/octet-stream"
+
+	// The maximum amount of memory used to hold multipart form files before they are stored on disk.
+	MultipartMaxMemory = 32 << 20
+)
+
+// A media type which has been declared on a request body.
+type requestBodyMedia struct {
+	mimeType   string
+	bodyType   reflect.Type
+	jsonSchema json.RawMessage
+	schemaName string
+}
+
+func (o *openAPI) SetMaxRequestBodySize(size int64) {
+	o.maxRequestBodySize = size
+}
+
+func (e *endpointObject) RequestBodyContent(mimeType, description string, required bool, schema, object interface{}) EndpointDeclaration {
+	if mimeType == MimeOctetStream {
+		schema = map[string]string{"type": "string", "format": "binary"}
+	} else if schema == nil {
+		var err error
+		if schema, err = e.spec.SchemaOf(object); err != nil {
+			e.err = errors.WithMessage(err, "failed to generate request body schema: "+e.doc.OperationId+" "+mimeType)
+			return e
+		}
+	}
+
+	// Handle jsonschema and swagger schemas including references.
+	b, err := json.Marshal(schema)
+	if err != nil {
+		e.err = errors.WithMessage(err, "failed to marshal request body schema: "+e.doc.OperationId+" "+mimeType)
+		return e
+	}
+	media := &requestBodyMedia{
+		mimeType:   mimeType,
+		bodyType:   reflect.TypeOf(object),
+		jsonSchema: b,
+		schemaName: e.reqSchemaName + "_" + schemaNameRegex.ReplaceAllString(mimeType, "_"),
+	}
+	if mimeType == MimeOctetStream {
+		media.jsonSchema = nil
+	}
+	if schema, err = docSchema(b); err != nil {
+		e.err = errors.WithMessage(err, "failed to convert request body schema: "+e.doc.OperationId+" "+mimeType)
+		return e
+	}
+
+	// The description and requirement are shared by the media types of the request body.
+	if e.doc.RequestBody == nil {
+		e.doc.RequestBody = &oasm.RequestBody{
+			Content:  make(oasm.MediaTypesMap),
+			Required: required,
+		}
+	} else if e.doc.RequestBody.Required != required {
+		e.err = errors.New(fmt.Sprint("request body media types must all be required or optional: ",
+			e.doc.OperationId, " ", mimeType))
+		return e
+	}
+	if e.doc.RequestBody.Description == "" {
+		e.doc.RequestBody.Description = description
+	}
+	e.doc.RequestBody.Content[mimeType] = oasm.MediaType{
+		Schema: schema,
+	}
+	e.bodies[mimeType] = media
+	return e
+}
+
+// Find the declared media type matching the Content-Type of the request.
+// Requests without a Content-Type are assumed to be JSON.
+func (e *endpointObject) requestMedia(data *Data) (*requestBodyMedia, error) {
+	contentType := data.Req.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = oasm.MimeJson
+	}
+	mimeType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, newUnsupportedMediaTypeError(contentType, e.doc.RequestBody.Content)
+	}
+	media, ok := e.bodies[mimeType]
+	if !ok {
+		return nil, newUnsupportedMediaTypeError(contentType, e.doc.RequestBody.Content)
+	}
+	return media, nil
+}
+
+// Read the request body, limited to the maximum request body size, returning nil if it is empty.
+func (e *endpointObject) openRequestBody(data *Data) (*bufio.Reader, error) {
+	var body io.Reader = data.Req.Body
+	if e.spec.maxRequestBodySize > 0 {
+		body = http.MaxBytesReader(data.ResWriter, data.Req.Body, e.spec.maxRequestBodySize)
+	}
+	r := bufio.NewReader(body)
+	if _, err := r.Peek(1); err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, requestBodyError(err)
+	}
+	return r, nil
+}
+
+// Convert the request body into JSON for validation, returning the body as well unless it is a multipart form.
+// Multipart forms are streamed into Data.Files, whose temporary files are removed once the request has been handled.
+// Binary bodies are not validated, and are represented by null.
+func (e *endpointObject) requestBodyToJSON(data *Data, media *requestBodyMedia, body io.Reader) ([]byte, json.RawMessage, error) {
+	if media.mimeType == MimeMultipartForm {
+		_, params, _ := mime.ParseMediaType(data.Req.Header.Get("Content-Type"))
+		form, err := multipart.NewReader(body, params["boundary"]).ReadForm(MultipartMaxMemory)
+		if err != nil {
+			// The errors of parts do not keep their cause, so the rest of the body is read to find if it was too large.
+			if _, readErr := io.Copy(ioutil.Discard, body); readErr != nil {
+				return nil, nil, requestBodyError(readErr)
+			}
+			return nil, nil, newMalformedFormError(err)
+		}
+		data.form = form
+		data.Files = form.File
+		bodyJSON, err := e.formToJSON(media, form.Value, form.File)
+		return nil, bodyJSON, err
+	}
+
+	requestBody, err := ioutil.ReadAll(body)
+	if err != nil {
+		return nil, nil, requestBodyError(err)
+	}
+	var bodyJSON json.RawMessage
+	switch media.mimeType {
+	case MimeFormUrlEncoded:
+		values, err := url.ParseQuery(string(requestBody))
+		if err != nil {
+			return nil, nil, newMalformedFormError(err)
+		}
+		bodyJSON, err = e.formToJSON(media, values, nil)
+		return requestBody, bodyJSON, err
+	case MimeTextPlain:
+		bodyJSON, err = json.Marshal(string(requestBody))
+		return requestBody, bodyJSON, err
+	case MimeOctetStream:
+		return requestBody, json.RawMessage("null"), nil
+	}
+	return requestBody, requestBody, nil
+}
+
+// Convert an error from reading the request body into a 413 problem if the body was larger than the limit.
+func requestBodyError(err error) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return NewProblem(http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("The request body is larger than the limit of %v bytes.", tooLarge.Limit))
+	}
+	return errors.WithMessage(err, "failed to read request body")
+}
+
+// Set data.Body from the request body, using the JSON which was created for validation.
+func (e *endpointObject) readRequestBody(data *Data, media *requestBodyMedia, requestBody []byte, bodyJSON json.RawMessage) error {
+	if media.mimeType == MimeOctetStream {
+		data.Body = requestBody
+		return nil
+	}
+	if media.bodyType == nil {
+		var body interface{}
+		if err := json.Unmarshal(bodyJSON, &body); err != nil {
+			return newMalformedJSONError(err)
+		}
+		data.Body = body
+		return nil
+	}
+	data.Body = reflect.New(media.bodyType).Interface()
+	if err := json.Unmarshal(bodyJSON, data.Body); err != nil {
+		return newMalformedJSONError(err)
+	}
+	return nil
+}
+
+// Convert form values into a JSON object, using the body schema to decide the type of each value.
+// Files are represented by their file names.
+func (e *endpointObject) formToJSON(media *requestBodyMedia, values map[string][]string, files map[string][]*multipart.FileHeader) (json.RawMessage, error) {
+	properties, _ := e.spec.resolveSchema(media.jsonSchema)["properties"].(map[string]interface{})
+	object := make(map[string]interface{}, len(values)+len(files))
+	for name, fileHeaders := range files {
+		fileNames := make([]string, 0, len(fileHeaders))
+		for _, f := range fileHeaders {
+			fileNames = append(fileNames, f.Filename)
+		}
+		values[name] = append(values[name], fileNames...)
+	}
+	for name, items := range values {
+		property := e.spec.resolveSchema(properties[name])
+		if schemaType(property) == "array" {
+			itemType := schemaType(e.spec.resolveSchema(property["items"]))
+			converted := make([]interface{}, 0, len(items))
+			for _, item := range items {
+				converted = append(converted, stringToJSONType(itemType, item))
+			}
+			object[name] = converted
+		} else if len(items) > 0 {
+			object[name] = stringToJSONType(schemaType(property), items[0])
+		}
+	}
+	return json.Marshal(object)
+}
+
+// Find the schema that a schema refers to, following references to registered schemas.
+// Unresolvable schemas result in an empty schema.
+func (o *openAPI) resolveSchema(schema interface{}) map[string]interface{} {
+	var s map[string]interface{}
+	switch schema := schema.(type) {
+	case map[string]interface{}:
+		s = schema
+	case json.RawMessage:
+		_ = json.Unmarshal(schema, &s)
+	}
+	for i := 0; i < 10 && s != nil; i++ {
+		ref, ok := s["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "{") || !strings.HasSuffix(ref, "}") {
+			break
+		}
+		s = nil
+		_ = json.Unmarshal(o.validatorBuilder.GetSchemas()[ref[1:len(ref)-1]], &s)
+	}
+	if s == nil {
+		return map[string]interface{}{}
+	}
+	return s
+}
+
+// Convert a string into a value of the JSON type, leaving it as a string if it cannot be converted.
+func stringToJSONType(typ, value string) interface{} {
+	switch typ {
+	case "integer":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i
+		}
+	case "number":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
@@ -0,0 +1,166 @@
+package oas
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime/multipart"
+	"strings"
+	"testing"
+
+	"github.com/tjbrockmeyer/oasm"
+)
+
+// Create a multipart form with a name field and a file field.
+func multipartBody(t *testing.T, name, fileContent string) (*bytes.Buffer, string) {
+	t.Helper()
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+	if err := w.WriteField("name", name); err != nil {
+		t.Fatal(err)
+	}
+	f, err := w.CreateFormFile("file", "upload.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = f.Write([]byte(fileContent)); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return body, w.FormDataContentType()
+}
+
+func TestRequestBodyLimits(t *testing.T) {
+	spec := newTestSpec(t)
+	spec.SetMaxRequestBodySize(1024)
+	formSchema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name": map[string]interface{}{"type": "string"},
+			"file": map[string]interface{}{"type": "string", "format": "binary"},
+		},
+	}
+	spec.NewEndpoint("upload", "POST", "/upload", "", "", nil).
+		RequestBodyContent(MimeMultipartForm, "The upload.", true, formSchema, nil).
+		RequestBodyContent(MimeTextPlain, "The note.", true, map[string]interface{}{"type": "string"}, nil).
+		Response(200, "The contents.", nil).
+		MustDefine(func(d Data) (interface{}, error) {
+			if d.Files == nil {
+				return d.Body, nil
+			}
+			f, err := d.Files["file"][0].Open()
+			if err != nil {
+				return nil, err
+			}
+			defer f.Close()
+			b, err := ioutil.ReadAll(f)
+			return string(b), err
+		})
+
+	smallForm, smallType := multipartBody(t, "small", "file contents")
+	largeForm, largeType := multipartBody(t, "large", strings.Repeat("x", 2048))
+	tests := []struct {
+		name        string
+		body        *bytes.Buffer
+		contentType string
+		status      int
+		want        string
+	}{
+		{"multipart form", smallForm, smallType, 200, `"file contents"`},
+		{"multipart form over the limit", largeForm, largeType, 413, ""},
+		{"text", bytes.NewBufferString("note"), MimeTextPlain, 200, `"note"`},
+		{"text over the limit", bytes.NewBufferString(strings.Repeat("x", 2048)), MimeTextPlain, 413, ""},
+		{"malformed multipart form", bytes.NewBufferString("--x\r\nbroken"), MimeMultipartForm + "; boundary=x", 400, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(spec, "POST", "/api/upload", tt.body, map[string]string{"Content-Type": tt.contentType})
+			assertStatus(t, w, tt.status)
+			if tt.want != "" && strings.TrimSpace(w.Body.String()) != tt.want {
+				t.Fatalf("expected body %s, got %s", tt.want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequestBodyContentSharedFields(t *testing.T) {
+	schema := map[string]interface{}{"type": "string"}
+	tests := []struct {
+		name        string
+		declare     func(EndpointDeclaration) EndpointDeclaration
+		description string
+		required    bool
+		wantErr     string
+	}{
+		{"single media type", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.RequestBodyContent(MimeTextPlain, "The note.", true, schema, nil)
+		}, "The note.", true, ""},
+		{"first description is kept", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.RequestBodyContent(MimeTextPlain, "The note.", false, schema, nil).
+				RequestBodyContent(oasm.MimeJson, "The JSON note.", false, schema, nil)
+		}, "The note.", false, ""},
+		{"first non-empty description is kept", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.RequestBodyContent(MimeTextPlain, "", true, schema, nil).
+				RequestBodyContent(oasm.MimeJson, "The JSON note.", true, schema, nil)
+		}, "The JSON note.", true, ""},
+		{"conflicting requirement", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.RequestBodyContent(MimeTextPlain, "The note.", true, schema, nil).
+				RequestBodyContent(oasm.MimeJson, "The note.", false, schema, nil)
+		}, "", false, "must all be required or optional"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTestSpec(t)
+			e, err := tt.declare(spec.NewEndpoint("createNote", "POST", "/notes", "", "", nil).Response(200, "The note.", nil)).
+				Define(func(Data) (interface{}, error) {
+					return nil, nil
+				})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			body := e.Doc().RequestBody
+			if body.Description != tt.description || body.Required != tt.required {
+				t.Fatalf("expected %q and required %v, got %q and %v", tt.description, tt.required, body.Description, body.Required)
+			}
+		})
+	}
+}
+
+func TestRequestBodyLimitDefault(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int64
+		status int
+		has413 bool
+	}{
+		{"no limit by default", -1, 200, false},
+		{"limit", 1024, 413, true},
+		{"limit removed", 0, 200, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTestSpec(t)
+			if tt.limit >= 0 {
+				spec.SetMaxRequestBodySize(tt.limit)
+			}
+			e := spec.NewEndpoint("createNote", "POST", "/notes", "", "", nil).
+				RequestBodyContent(MimeTextPlain, "The note.", true, map[string]interface{}{"type": "string"}, nil).
+				Response(200, "The note.", nil).
+				MustDefine(func(Data) (interface{}, error) {
+					return nil, nil
+				})
+			body := strings.Repeat("x", 33<<20)
+			assertStatus(t, serve(spec, "POST", "/api/notes", strings.NewReader(body), map[string]string{"Content-Type": MimeTextPlain}), tt.status)
+			if _, ok := e.Doc().Responses.Codes[413]; ok != tt.has413 {
+				t.Fatalf("expected the 413 response to be documented: %v", tt.has413)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package oas
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Get the default value of the parameter's schema, converted into the kind of the parameter.
+func (p typedParameter) defaultValue() (interface{}, bool) {
+	d, ok := p.schema["default"]
+	if !ok {
+		return nil, false
+	}
+	return p.defaultToKind(p.kind, p.schema, d), true
+}
+
+// Convert a default value from a schema into the kind that the parameter would have been converted into.
+func (p typedParameter) defaultToKind(kind reflect.Kind, schema map[string]interface{}, value interface{}) interface{} {
+	switch v := value.(type) {
+	case float64:
+		switch kind {
+		case reflect.Int:
+			return int(v)
+		case reflect.Int64:
+			return int64(v)
+		case reflect.Uint:
+			return uint(v)
+		case reflect.Float32:
+			return float32(v)
+		}
+	case []interface{}:
+		items, _ := schema["items"].(map[string]interface{})
+		converted := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			converted = append(converted, p.defaultToKind(schemaKind(items), items, item))
+		}
+		return converted
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		converted := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			propertySchema := p.propertySchema(properties, k)
+			converted[k] = p.defaultToKind(schemaKind(propertySchema), propertySchema, item)
+		}
+		return converted
+	}
+	return value
+}
+
+// Add the default values of properties (including those of nested objects) which are missing from a JSON body.
+func (o *openAPI) applyBodyDefaults(schema json.RawMessage, body json.RawMessage) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, newMalformedJSONError(err)
+	}
+	if !o.applyDefaults(schema, value) {
+		return body, nil
+	}
+	return json.Marshal(value)
+}
+
+// Add the default values of missing properties to the value, returning true if any were added.
+func (o *openAPI) applyDefaults(schema interface{}, value interface{}) bool {
+	s := o.resolveSchema(schema)
+	changed := false
+	if allOf, ok := s["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			changed = o.applyDefaults(sub, value) || changed
+		}
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		properties, _ := s["properties"].(map[string]interface{})
+		for k, property := range properties {
+			if item, ok := v[k]; ok {
+				changed = o.applyDefaults(property, item) || changed
+			} else if d, ok := o.resolveSchema(property)["default"]; ok {
+				v[k] = d
+				changed = true
+			}
+		}
+	case []interface{}:
+		if items, ok := s["items"]; ok {
+			for _, item := range v {
+				changed = o.applyDefaults(items, item) || changed
+			}
+		}
+	}
+	return changed
+}
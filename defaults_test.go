@@ -0,0 +1,123 @@
+package oas
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParameterDefaults(t *testing.T) {
+	spec := newTestSpec(t)
+	spec.NewEndpoint("listItems", "GET", "/items", "", "", nil).
+		Parameter("query", "limit", "", false, map[string]interface{}{"type": "integer", "default": 20}, reflect.Int).
+		Parameter("query", "big", "", false, map[string]interface{}{"type": "integer", "format": "int64", "default": 5}, reflect.Int64).
+		Parameter("query", "ids", "", false, map[string]interface{}{
+			"type": "array", "items": map[string]interface{}{"type": "integer"}, "default": []interface{}{1, 2},
+		}, reflect.Slice).
+		Parameter("query", "filter", "", false, map[string]interface{}{
+			"type": "object", "properties": map[string]interface{}{"size": map[string]interface{}{"type": "integer"}},
+			"default": map[string]interface{}{"size": 3},
+		}, reflect.Map).
+		Parameter("header", "X-Mode", "", false, map[string]interface{}{"type": "string", "default": "fast"}, reflect.String).
+		Parameter("cookie", "theme", "", false, map[string]interface{}{"type": "string", "default": "dark"}, reflect.String).
+		Parameter("query", "sort", "", false, map[string]interface{}{"type": "string"}, reflect.String).
+		Response(200, "The parameters.", nil).
+		MustDefine(func(d Data) (interface{}, error) {
+			return MapAny{
+				"limit":  reflect.TypeOf(d.Query["limit"]).String(),
+				"big":    reflect.TypeOf(d.Query["big"]).String(),
+				"ids":    d.Query["ids"],
+				"filter": d.Query["filter"],
+				"mode":   d.Headers["X-Mode"],
+				"theme":  d.Cookies["theme"],
+				"sort":   d.Query.GetOrElse("sort", "missing"),
+			}, nil
+		})
+
+	tests := []struct {
+		name    string
+		target  string
+		headers map[string]string
+		want    string
+	}{
+		{"defaults", "/api/items", nil,
+			`{"big":"int64","filter":{"size":3},"ids":[1,2],"limit":"int","mode":"fast","sort":"missing","theme":"dark"}`},
+		{"provided values", "/api/items?limit=5&ids=7&size=4&sort=name", map[string]string{"X-Mode": "slow", "Cookie": "theme=light"},
+			`{"big":"int64","filter":{"size":4},"ids":[7],"limit":"int","mode":"slow","sort":"name","theme":"light"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(spec, "GET", tt.target, nil, tt.headers)
+			assertStatus(t, w, 200)
+			if body := strings.Join(strings.Fields(w.Body.String()), ""); body != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, body)
+			}
+		})
+	}
+}
+
+type defaultedItem struct {
+	Name  string `json:"name"`
+	Count int    `json:"count,omitempty" oas:"default=1"`
+}
+
+func TestApplyBodyDefaults(t *testing.T) {
+	spec := newTestSpec(t)
+	itemRef, err := spec.SchemaOf(defaultedItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	object := func(properties map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"type": "object", "properties": properties}
+	}
+	withDefault := func(typ string, d interface{}) map[string]interface{} {
+		return map[string]interface{}{"type": typ, "default": d}
+	}
+	tests := []struct {
+		name   string
+		schema interface{}
+		body   string
+		want   string
+	}{
+		{"missing property", object(map[string]interface{}{"limit": withDefault("integer", 20)}), `{}`, `{"limit":20}`},
+		{"present property", object(map[string]interface{}{"limit": withDefault("integer", 20)}), `{"limit":5}`, `{"limit":5}`},
+		{"null property", object(map[string]interface{}{"limit": withDefault("integer", 20)}), `{"limit":null}`, `{"limit":null}`},
+		{"nested object", object(map[string]interface{}{
+			"page": object(map[string]interface{}{"size": withDefault("integer", 10)}),
+		}), `{"page":{}}`, `{"page":{"size":10}}`},
+		{"missing nested object", object(map[string]interface{}{
+			"page": object(map[string]interface{}{"size": withDefault("integer", 10)}),
+		}), `{}`, `{}`},
+		{"items of an array", map[string]interface{}{
+			"type": "array", "items": object(map[string]interface{}{"tag": withDefault("string", "new")}),
+		}, `[{},{"tag":"old"}]`, `[{"tag":"new"},{"tag":"old"}]`},
+		{"all of", map[string]interface{}{"allOf": []interface{}{
+			object(map[string]interface{}{"a": withDefault("string", "x")}),
+			object(map[string]interface{}{"b": withDefault("string", "y")}),
+		}}, `{}`, `{"a":"x","b":"y"}`},
+		{"reference", itemRef, `{"name":"a"}`, `{"count":1,"name":"a"}`},
+		{"large numbers are kept", object(map[string]interface{}{"limit": withDefault("integer", 20)}),
+			`{"id":9007199254740993}`, `{"id":9007199254740993,"limit":20}`},
+		{"not an object", object(map[string]interface{}{"limit": withDefault("integer", 20)}), `"text"`, `"text"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schema, err := json.Marshal(tt.schema)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := spec.(*openAPI).applyBodyDefaults(schema, json.RawMessage(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	if _, err = spec.(*openAPI).applyBodyDefaults(json.RawMessage(`{}`), json.RawMessage(`{`)); err == nil {
+		t.Fatal("expected malformed JSON to fail")
+	}
+}
@@ -0,0 +1,159 @@
+package oas
+
+import (
+	"bytes"
+	"embed"
+	"github.com/pkg/errors"
+	"html/template"
+	"io"
+	"io/fs"
+)
+
+// The scripts of ReDoc and RapiDoc are embedded in the binary, like the Swagger UI, and are served along with the page.
+// They are pinned to the versions of these URLs, which may be used instead to load them from the jsDelivr CDN.
+const (
+	// The URL of the ReDoc script on the CDN. See: ReDocUI
+	ReDocScriptURL = "https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js"
+	// The URL of the RapiDoc script on the CDN. See: RapiDocUI
+	RapiDocScriptURL = "https://cdn.jsdelivr.net/npm/rapidoc@9.3.4/dist/rapidoc-min.js"
+)
+
+// The file names of the embedded scripts, which are downloaded from the CDN by go generate.
+const (
+	redocScriptFile   = "redoc.standalone.js"
+	rapidocScriptFile = "rapidoc-min.js"
+)
+
+//go:generate curl -fsSL -o docs-dist/redoc.standalone.js https://cdn.jsdelivr.net/npm/redoc@2.1.5/bundles/redoc.standalone.js
+//go:generate curl -fsSL -o docs-dist/rapidoc-min.js https://cdn.jsdelivr.net/npm/rapidoc@9.3.4/dist/rapidoc-min.js
+
+//go:embed docs-dist
+var docsDist embed.FS
+
+// The embedded scripts of ReDoc and RapiDoc.
+var docsScripts, _ = fs.Sub(docsDist, "docs-dist")
+
+//go:embed docs-templates
+var docsTemplates embed.FS
+
+var docsUITemplates = template.Must(template.ParseFS(docsTemplates, "docs-templates/*.html"))
+
+// A documentation UI which is served by the file server of the spec.
+type DocsUI struct {
+	// Write the index page of the UI, which shows the spec at the URL.
+	Index func(w io.Writer, specURL string) error
+	// The other files of the UI, which are served by their name. May be nil.
+	Files fs.FS
+}
+
+// The script of a ReDoc or RapiDoc UI.
+type DocsScript struct {
+	// The URL of the script. A relative URL, such as "redoc.standalone.js", refers to a file of Files.
+	URL string
+	// The Subresource Integrity hash of the script, such as "sha384-...", which browsers check before running it.
+	// It is not checked if it is empty.
+	Integrity string
+	// Files which are served by the file server of the spec along with the UI, such as the script itself. May be nil.
+	Files fs.FS
+}
+
+func (o *openAPI) SetDocsUI(ui *DocsUI) {
+	o.docsUI = ui
+}
+
+// Get the documentation UI of the spec, which is the Swagger UI unless another UI was set.
+func (o *openAPI) currentDocsUI() (DocsUI, error) {
+	if o.docsUI != nil {
+		return *o.docsUI, nil
+	}
+	files, err := swaggerUIFiles()
+	if err != nil {
+		return DocsUI{}, err
+	}
+	return DocsUI{
+		Index: func(w io.Writer, specURL string) error {
+			options := o.swaggerUIOptions
+			if options.SpecURL == "" {
+				options.SpecURL = specURL
+			}
+			return swaggerUIIndex.Execute(w, options.indexData())
+		},
+		Files: files,
+	}, nil
+}
+
+// Create a documentation UI which uses ReDoc to show the spec.
+// The script is loaded from the URL (such as ReDocScriptURL), or is the embedded script if it is empty.
+// See: ReDocScriptUI
+func ReDocUI(title, scriptURL string) *DocsUI {
+	return ReDocScriptUI(title, DocsScript{URL: scriptURL})
+}
+
+// Create a documentation UI which uses RapiDoc to show the spec.
+// The script is loaded from the URL (such as RapiDocScriptURL), or is the embedded script if it is empty.
+// See: RapiDocScriptUI
+func RapiDocUI(title, scriptURL string) *DocsUI {
+	return RapiDocScriptUI(title, DocsScript{URL: scriptURL})
+}
+
+// Create a documentation UI which uses ReDoc to show the spec, serving the embedded script if the URL is empty.
+func ReDocScriptUI(title string, script DocsScript) *DocsUI {
+	if script.URL == "" {
+		script = embeddedDocsScript(redocScriptFile)
+	}
+	return scriptDocsUI("redoc.html", title, script)
+}
+
+// Create a documentation UI which uses RapiDoc to show the spec, serving the embedded script if the URL is empty.
+func RapiDocScriptUI(title string, script DocsScript) *DocsUI {
+	if script.URL == "" {
+		script = embeddedDocsScript(rapidocScriptFile)
+	}
+	return scriptDocsUI("rapidoc.html", title, script)
+}
+
+// Get an embedded script, which is served as the only file of the UI.
+func embeddedDocsScript(name string) DocsScript {
+	return DocsScript{URL: name, Files: singleFileFS{docsScripts, name}}
+}
+
+// A file system which contains a single file of another file system.
+type singleFileFS struct {
+	fs   fs.FS
+	name string
+}
+
+func (f singleFileFS) Open(name string) (fs.File, error) {
+	if name != f.name {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return f.fs.Open(name)
+}
+
+// Create a documentation UI from the template of a page that shows the spec using a single script.
+// If the script is embedded, the page is not written unless the script was downloaded when the package was built.
+func scriptDocsUI(templateName, title string, script DocsScript) *DocsUI {
+	return &DocsUI{
+		Index: func(w io.Writer, specURL string) error {
+			if embedded, ok := script.Files.(singleFileFS); ok {
+				if _, err := fs.Stat(embedded, embedded.name); err != nil {
+					return errors.WithMessage(err, "the script is not embedded, so it must be downloaded with go generate, "+
+						"or loaded from the CDN: "+embedded.name)
+				}
+			}
+			var buf bytes.Buffer
+			err := docsUITemplates.ExecuteTemplate(&buf, templateName, map[string]string{
+				"Title":           title,
+				"SpecURL":         specURL,
+				"ScriptURL":       script.URL,
+				"ScriptIntegrity": script.Integrity,
+			})
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(buf.Bytes())
+			return err
+		},
+		Files: script.Files,
+	}
+}
@@ -0,0 +1,94 @@
+package oas
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestScriptDocsUI(t *testing.T) {
+	embedded := docsScripts
+	defer func() { docsScripts = embedded }()
+	docsScripts = fstest.MapFS{
+		"redoc.standalone.js": {Data: []byte("// embedded redoc")},
+		"rapidoc-min.js":      {Data: []byte("// embedded rapidoc")},
+	}
+	files := fstest.MapFS{"redoc.standalone.js": {Data: []byte("// redoc")}}
+	tests := []struct {
+		name     string
+		ui       *DocsUI
+		file     string
+		contains []string
+		excludes []string
+	}{
+		{"embedded ReDoc", ReDocUI("Docs", ""), "index.html",
+			[]string{`<script src="redoc.standalone.js"> </script>`, `spec-url="./openapi.json"`},
+			[]string{"cdn.jsdelivr.net", "integrity"}},
+		{"embedded ReDoc script", ReDocUI("Docs", ""), "redoc.standalone.js", []string{"// embedded redoc"}, nil},
+		{"embedded RapiDoc", RapiDocUI("Docs", ""), "index.html",
+			[]string{`src="rapidoc-min.js"`}, []string{"cdn.jsdelivr.net"}},
+		{"embedded RapiDoc script", RapiDocUI("Docs", ""), "rapidoc-min.js", []string{"// embedded rapidoc"}, nil},
+		{"ReDoc from the CDN", ReDocUI("Docs", ReDocScriptURL), "index.html",
+			[]string{`<script src="` + ReDocScriptURL + `"> </script>`}, []string{"integrity"}},
+		{"RapiDoc from the CDN", RapiDocUI("Docs", RapiDocScriptURL), "index.html",
+			[]string{`src="` + RapiDocScriptURL + `"`}, []string{"integrity"}},
+		{"ReDoc with integrity", ReDocScriptUI("Docs", DocsScript{URL: ReDocScriptURL, Integrity: "sha384-abc"}), "index.html",
+			[]string{`integrity="sha384-abc" crossorigin="anonymous"`}, nil},
+		{"RapiDoc with integrity", RapiDocScriptUI("Docs", DocsScript{URL: RapiDocScriptURL, Integrity: "sha384-abc"}), "index.html",
+			[]string{`src="` + RapiDocScriptURL + `" integrity="sha384-abc" crossorigin="anonymous"`}, nil},
+		{"ReDoc with a served script", ReDocScriptUI("Docs", DocsScript{URL: "redoc.standalone.js", Files: files}), "index.html",
+			[]string{`<script src="redoc.standalone.js"> </script>`}, []string{"cdn.jsdelivr.net"}},
+		{"served script", ReDocScriptUI("Docs", DocsScript{URL: "redoc.standalone.js", Files: files}), "redoc.standalone.js",
+			[]string{"// redoc"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, fileServer, err := NewOpenAPI("Test", "", "http://localhost/api", "1.0.0", t.TempDir(), nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec.SetDocsUI(tt.ui)
+			w := httptest.NewRecorder()
+			fileServer.ServeHTTP(w, httptest.NewRequest("GET", "/docs/"+strings.TrimSuffix(tt.file, "index.html"), nil))
+			assertStatus(t, w, 200)
+			for _, s := range tt.contains {
+				if !strings.Contains(w.Body.String(), s) {
+					t.Fatalf("expected the page to contain %s: %s", s, w.Body.String())
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(w.Body.String(), s) {
+					t.Fatalf("expected the page not to contain %s: %s", s, w.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func TestScriptDocsUIWithoutEmbeddedScript(t *testing.T) {
+	embedded := docsScripts
+	defer func() { docsScripts = embedded }()
+	docsScripts = fstest.MapFS{}
+
+	tests := []struct {
+		name string
+		ui   *DocsUI
+	}{
+		{"ReDoc", ReDocUI("Docs", "")},
+		{"RapiDoc", RapiDocUI("Docs", "")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := tt.ui.Index(&buf, "./openapi.json")
+			if err == nil || !strings.Contains(err.Error(), "go generate") {
+				t.Fatalf("expected an error which explains how to get the script, got %v", err)
+			}
+			if buf.Len() != 0 {
+				t.Fatalf("expected no page to be written: %s", buf.String())
+			}
+		})
+	}
+}
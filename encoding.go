@@ -0,0 +1,366 @@
+package oas
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/oasm"
+	"gopkg.in/yaml.v3"
+	"math"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MimeYaml    = "application/yaml"
+	MimeXml     = "application/xml"
+	MimeMsgpack = "application/msgpack"
+	MimeCsv     = "text/csv"
+)
+
+// Encodes a response body for a single media type.
+// Indent is the requested indent level, which may be ignored by formats that do not support it.
+// An error wrapping ErrUnsupportedBody means that the body cannot be represented in the media type,
+// in which case the next media type accepted by the request is used, or 406 Not Acceptable is sent.
+type ResponseEncoder func(body interface{}, indent int) ([]byte, error)
+
+// Returned (wrapped) by a ResponseEncoder when the body cannot be represented in its media type.
+var ErrUnsupportedBody = errors.New("the body cannot be encoded as the media type")
+
+func defaultResponseEncoders() map[string]ResponseEncoder {
+	return map[string]ResponseEncoder{
+		oasm.MimeJson: EncodeJSON,
+		MimeYaml:      EncodeYAML,
+		MimeXml:       EncodeXML,
+		MimeMsgpack:   EncodeMsgpack,
+		MimeCsv:       EncodeCSV,
+		MimeTextPlain: EncodeText,
+	}
+}
+
+// Encode the body as JSON.
+func EncodeJSON(body interface{}, indent int) ([]byte, error) {
+	if indent > 0 {
+		return json.MarshalIndent(body, "", strings.Repeat(" ", indent))
+	}
+	return json.Marshal(body)
+}
+
+// Encode the body as YAML, using the same field names as its JSON encoding.
+func EncodeYAML(body interface{}, indent int) ([]byte, error) {
+	generic, err := toGenericJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	if indent > 0 {
+		encoder.SetIndent(indent)
+	}
+	if err = encoder.Encode(generic); err != nil {
+		return nil, err
+	}
+	if err = encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encode the body as XML using encoding/xml.
+// Arrays are wrapped in a <response> element, with each item in an <item> element.
+// Bodies containing maps, or other types which encoding/xml does not support, are not encoded.
+func EncodeXML(body interface{}, indent int) ([]byte, error) {
+	if v := reflect.ValueOf(body); (v.Kind() == reflect.Slice || v.Kind() == reflect.Array) && v.Type().Elem().Kind() != reflect.Uint8 {
+		body = struct {
+			XMLName xml.Name    `xml:"response"`
+			Items   interface{} `xml:"item"`
+		}{Items: body}
+	}
+	var b []byte
+	var err error
+	if indent > 0 {
+		b, err = xml.MarshalIndent(body, "", strings.Repeat(" ", indent))
+	} else {
+		b, err = xml.Marshal(body)
+	}
+	var unsupported *xml.UnsupportedTypeError
+	if errors.As(err, &unsupported) {
+		return nil, errors.WithMessage(ErrUnsupportedBody, err.Error())
+	}
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), b...), nil
+}
+
+// Encode the body as MessagePack, using the same field names as its JSON encoding.
+func EncodeMsgpack(body interface{}, _ int) ([]byte, error) {
+	generic, err := toGenericJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = writeMsgpack(&buf, generic); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Encode an array body as CSV.
+// Arrays of objects have a header row containing all of their keys, and any other values are written in a single column.
+// Nested arrays and objects are written as JSON.
+func EncodeCSV(body interface{}, _ int) ([]byte, error) {
+	generic, err := toGenericJSON(body)
+	if err != nil {
+		return nil, err
+	}
+	rows, ok := generic.([]interface{})
+	if !ok {
+		return nil, errors.WithMessage(ErrUnsupportedBody, "csv responses must be arrays")
+	}
+
+	var columns []string
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		if object, ok := row.(map[string]interface{}); ok {
+			for k := range object {
+				if !seen[k] {
+					seen[k] = true
+					columns = append(columns, k)
+				}
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	if len(columns) > 0 {
+		if err = writer.Write(columns); err != nil {
+			return nil, err
+		}
+	}
+	for _, row := range rows {
+		var record []string
+		if object, ok := row.(map[string]interface{}); ok {
+			record = make([]string, 0, len(columns))
+			for _, k := range columns {
+				record = append(record, csvValue(object[k]))
+			}
+		} else {
+			record = []string{csvValue(row)}
+		}
+		if err = writer.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	writer.Flush()
+	return buf.Bytes(), writer.Error()
+}
+
+// Encode the body as plain text.
+func EncodeText(body interface{}, _ int) ([]byte, error) {
+	switch body := body.(type) {
+	case []byte:
+		return body, nil
+	case string:
+		return []byte(body), nil
+	case fmt.Stringer:
+		return []byte(body.String()), nil
+	}
+	return []byte(fmt.Sprint(body)), nil
+}
+
+// Choose the media types which may be responded with, in order of preference,
+// given the Accept header of the request and the available media types.
+// Each media type has the quality of the most specific range which matches it, so that it is excluded by q=0,
+// and media types of equal quality are ordered by the ranges which match them.
+// The available media types are kept in order when the client does not have a preference.
+func negotiateMediaTypes(accept string, available []string) []string {
+	if strings.TrimSpace(accept) == "" {
+		return available
+	}
+	type mediaRange struct {
+		mimeType string
+		q        float64
+	}
+	ranges := make([]mediaRange, 0, 4)
+	for _, part := range strings.Split(accept, ",") {
+		mimeType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qs, 64); err != nil {
+				continue
+			}
+		}
+		ranges = append(ranges, mediaRange{mimeType, q})
+	}
+
+	type acceptable struct {
+		mimeType string
+		q        float64
+		index    int
+	}
+	accepted := make([]acceptable, 0, len(available))
+	for _, mimeType := range available {
+		match, specificity := -1, 0
+		for i, r := range ranges {
+			s := 0
+			switch {
+			case r.mimeType == mimeType:
+				s = 3
+			case strings.HasSuffix(r.mimeType, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(r.mimeType, "*")):
+				s = 2
+			case r.mimeType == "*/*":
+				s = 1
+			}
+			if s > specificity {
+				match, specificity = i, s
+			}
+		}
+		if match >= 0 && ranges[match].q > 0 {
+			accepted = append(accepted, acceptable{mimeType, ranges[match].q, match})
+		}
+	}
+	sort.SliceStable(accepted, func(i, j int) bool {
+		if accepted[i].q != accepted[j].q {
+			return accepted[i].q > accepted[j].q
+		}
+		return accepted[i].index < accepted[j].index
+	})
+	mimeTypes := make([]string, 0, len(accepted))
+	for _, a := range accepted {
+		mimeTypes = append(mimeTypes, a.mimeType)
+	}
+	return mimeTypes
+}
+
+// Convert a value into the generic form of its JSON encoding.
+func toGenericJSON(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	var generic interface{}
+	if err = decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return numbersToGo(generic), nil
+}
+
+// Replace json.Numbers with int64 or float64 values.
+func numbersToGo(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case []interface{}:
+		for i, item := range v {
+			v[i] = numbersToGo(item)
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = numbersToGo(item)
+		}
+	}
+	return v
+}
+
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []interface{}, map[string]interface{}:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// Write a generic JSON value as MessagePack.
+func writeMsgpack(buf *bytes.Buffer, v interface{}) error {
+	switch v := v.(type) {
+	case nil:
+		buf.WriteByte(0xc0)
+	case bool:
+		if v {
+			buf.WriteByte(0xc3)
+		} else {
+			buf.WriteByte(0xc2)
+		}
+	case int64:
+		switch {
+		case v >= 0 && v <= math.MaxInt8, v < 0 && v >= -32:
+			buf.WriteByte(byte(v))
+		default:
+			buf.WriteByte(0xd3)
+			_ = binary.Write(buf, binary.BigEndian, v)
+		}
+	case float64:
+		buf.WriteByte(0xcb)
+		_ = binary.Write(buf, binary.BigEndian, v)
+	case string:
+		writeMsgpackHeader(buf, len(v), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buf.WriteString(v)
+	case []interface{}:
+		writeMsgpackHeader(buf, len(v), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range v {
+			if err := writeMsgpack(buf, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeMsgpackHeader(buf, len(v), 0x80, 15, 0, 0xde, 0xdf)
+		for _, k := range keys {
+			if err := writeMsgpack(buf, k); err != nil {
+				return err
+			}
+			if err := writeMsgpack(buf, v[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("cannot encode %T as msgpack", v)
+	}
+	return nil
+}
+
+// Write the header of a msgpack string, array, or map with the given length.
+// A code of 0 indicates that the format does not have an 8 bit length variant.
+func writeMsgpackHeader(buf *bytes.Buffer, length int, fixCode byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case length <= fixMax:
+		buf.WriteByte(fixCode | byte(length))
+	case code8 != 0 && length <= math.MaxUint8:
+		buf.WriteByte(code8)
+		buf.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buf.WriteByte(code16)
+		_ = binary.Write(buf, binary.BigEndian, uint16(length))
+	default:
+		buf.WriteByte(code32)
+		_ = binary.Write(buf, binary.BigEndian, uint32(length))
+	}
+}
@@ -0,0 +1,269 @@
+package oas
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Decode a MessagePack value of the formats which are written by EncodeMsgpack.
+func decodeMsgpack(r *bytes.Reader) (interface{}, error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	readUint := func(size int) (int, error) {
+		b := make([]byte, size)
+		if _, err := r.Read(b); err != nil {
+			return 0, err
+		}
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return int(n), nil
+	}
+	readString := func(length int) (interface{}, error) {
+		b := make([]byte, length)
+		if _, err := r.Read(b); err != nil && length > 0 {
+			return nil, err
+		}
+		return string(b), nil
+	}
+	readArray := func(length int) (interface{}, error) {
+		array := make([]interface{}, 0, length)
+		for i := 0; i < length; i++ {
+			item, err := decodeMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		return array, nil
+	}
+	readMap := func(length int) (interface{}, error) {
+		m := make(map[string]interface{}, length)
+		for i := 0; i < length; i++ {
+			k, err := decodeMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+			if m[k.(string)], err = decodeMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+		return m, nil
+	}
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code&0xe0 == 0xa0:
+		return readString(int(code & 0x1f))
+	case code&0xf0 == 0x90:
+		return readArray(int(code & 0x0f))
+	case code&0xf0 == 0x80:
+		return readMap(int(code & 0x0f))
+	}
+	switch code {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xd3:
+		var v int64
+		return v, binary.Read(r, binary.BigEndian, &v)
+	case 0xcb:
+		var v float64
+		return v, binary.Read(r, binary.BigEndian, &v)
+	case 0xd9, 0xda, 0xdb:
+		n, err := readUint(map[byte]int{0xd9: 1, 0xda: 2, 0xdb: 4}[code])
+		if err != nil {
+			return nil, err
+		}
+		return readString(n)
+	case 0xdc, 0xdd:
+		n, err := readUint(map[byte]int{0xdc: 2, 0xdd: 4}[code])
+		if err != nil {
+			return nil, err
+		}
+		return readArray(n)
+	case 0xde, 0xdf:
+		n, err := readUint(map[byte]int{0xde: 2, 0xdf: 4}[code])
+		if err != nil {
+			return nil, err
+		}
+		return readMap(n)
+	}
+	return nil, fmt.Errorf("unexpected msgpack code 0x%x", code)
+}
+
+func TestEncodeMsgpackRoundTrip(t *testing.T) {
+	type item struct {
+		Name  string            `json:"name"`
+		Count int               `json:"count"`
+		Price float64           `json:"price"`
+		Tags  []string          `json:"tags"`
+		Attrs map[string]string `json:"attrs,omitempty"`
+		Next  *item             `json:"next"`
+	}
+	array := func(n int) []int {
+		a := make([]int, n)
+		for i := range a {
+			a[i] = i
+		}
+		return a
+	}
+	object := func(n int) map[string]bool {
+		m := make(map[string]bool, n)
+		for i := 0; i < n; i++ {
+			m[fmt.Sprint("key", i)] = i%2 == 0
+		}
+		return m
+	}
+
+	tests := []struct {
+		name string
+		body interface{}
+		code byte
+	}{
+		{"nil", nil, 0xc0},
+		{"true", true, 0xc3},
+		{"false", false, 0xc2},
+		{"zero", 0, 0x00},
+		{"largest positive fixint", 127, 0x7f},
+		{"smallest int64", 128, 0xd3},
+		{"smallest negative fixint", -32, 0xe0},
+		{"largest negative int64", -33, 0xd3},
+		{"min int64", int64(math.MinInt64), 0xd3},
+		{"max int64", int64(math.MaxInt64), 0xd3},
+		{"float", 1.5, 0xcb},
+		{"large float", 1e300, 0xcb},
+		{"empty string", "", 0xa0},
+		{"longest fixstr", strings.Repeat("a", 31), 0xbf},
+		{"shortest str8", strings.Repeat("a", 32), 0xd9},
+		{"longest str8", strings.Repeat("a", 255), 0xd9},
+		{"shortest str16", strings.Repeat("a", 256), 0xda},
+		{"shortest str32", strings.Repeat("a", 65536), 0xdb},
+		{"unicode string", "héllo, 世界", 0xa0 | byte(len("héllo, 世界"))},
+		{"empty array", []int{}, 0x90},
+		{"longest fixarray", array(15), 0x9f},
+		{"shortest array16", array(16), 0xdc},
+		{"shortest array32", array(65536), 0xdd},
+		{"empty map", map[string]int{}, 0x80},
+		{"longest fixmap", object(15), 0x8f},
+		{"shortest map16", object(16), 0xde},
+		{"struct", item{Name: "a", Count: 2, Price: 2.5, Tags: []string{"x"}, Next: &item{Name: "b", Count: -1000}}, 0x85},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := EncodeMsgpack(tt.body, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b[0] != tt.code {
+				t.Fatalf("expected the format 0x%x, got 0x%x", tt.code, b[0])
+			}
+			r := bytes.NewReader(b)
+			decoded, err := decodeMsgpack(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Len() > 0 {
+				t.Fatalf("%v bytes were not decoded", r.Len())
+			}
+			want, err := toGenericJSON(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(decoded, want) {
+				t.Fatalf("expected %v, got %v", want, decoded)
+			}
+		})
+	}
+}
+
+func TestNegotiateMediaTypes(t *testing.T) {
+	available := []string{"application/json", MimeXml, MimeCsv}
+	tests := []struct {
+		name   string
+		accept string
+		want   []string
+	}{
+		{"no preference", "", available},
+		{"any", "*/*", available},
+		{"exact", MimeXml, []string{MimeXml}},
+		{"order of the ranges", "text/csv, application/json", []string{MimeCsv, "application/json"}},
+		{"quality", "application/json;q=0.5, application/xml", []string{MimeXml, "application/json"}},
+		{"subtype wildcard", "application/*", []string{"application/json", MimeXml}},
+		{"excluded from any", "application/xml;q=0, */*", []string{"application/json", MimeCsv}},
+		{"excluded before any", "*/*;q=0.1, application/xml;q=0", []string{"application/json", MimeCsv}},
+		{"excluded subtype wildcard", "application/*;q=0, */*", []string{MimeCsv}},
+		{"exact over subtype wildcard", "application/*;q=0, application/json", []string{"application/json"}},
+		{"none acceptable", "image/png", []string{}},
+		{"all excluded", "*/*;q=0", []string{}},
+		{"malformed ranges are ignored", "application/;q=1, application/xml;q=x, text/csv", []string{MimeCsv}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateMediaTypes(tt.accept, available); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestResponseEncodingFallback(t *testing.T) {
+	type pet struct {
+		Name string `json:"name" xml:"name"`
+	}
+	spec := newTestSpec(t)
+	spec.NewEndpoint("getPet", "GET", "/pet", "", "", nil).
+		Response(200, "The pet.", nil).ResponseMediaTypes(200, MimeXml, MimeCsv, "application/json").
+		MustDefine(func(Data) (interface{}, error) {
+			return pet{Name: "Rex"}, nil
+		})
+	spec.NewEndpoint("getCounts", "GET", "/counts", "", "", nil).
+		Response(200, "The counts.", nil).ResponseMediaTypes(200, MimeXml, MimeCsv, "application/json").
+		MustDefine(func(Data) (interface{}, error) {
+			return map[string]int{"cats": 2}, nil
+		})
+	spec.NewEndpoint("getNames", "GET", "/names", "", "", nil).
+		Response(200, "The names.", nil).ResponseMediaTypes(200, MimeXml, MimeCsv).
+		MustDefine(func(Data) (interface{}, error) {
+			return map[string]string{"cat": "Tom"}, nil
+		})
+
+	tests := []struct {
+		name        string
+		target      string
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"struct as xml", "/api/pet", "", 200, MimeXml},
+		{"struct excluded from xml", "/api/pet", "application/xml;q=0, */*", 200, "application/json"},
+		{"struct excluded from every media type", "/api/pet", "application/xml;q=0, text/*", 406, MimeProblemJson},
+		{"map as json instead of xml", "/api/counts", "", 200, "application/json"},
+		{"map as json when xml is preferred", "/api/counts", "application/xml, application/json;q=0.1", 200, "application/json"},
+		{"map as xml only", "/api/counts", MimeXml, 406, MimeProblemJson},
+		{"map without a media type which can represent it", "/api/names", "", 406, MimeProblemJson},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(spec, "GET", tt.target, nil, map[string]string{"Accept": tt.accept})
+			assertStatus(t, w, tt.status)
+			if contentType := w.Header().Get("Content-Type"); contentType != tt.contentType {
+				t.Fatalf("expected the content type %s, got %s: %s", tt.contentType, contentType, w.Body.String())
+			}
+		})
+	}
+}
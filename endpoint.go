@@ -6,7 +6,6 @@ import (
 	"github.com/pkg/errors"
 	"github.com/tjbrockmeyer/oasm"
 	"github.com/tjbrockmeyer/vjsonschema"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"reflect"
@@ -22,21 +21,74 @@ type EndpointDeclaration interface {
 	// Set an arbitrary variable on this endpoint's Options object.
 	Set(key string, value interface{}) EndpointDeclaration
 	// Attach a parameter doc.
-	// Valid 'in's are query, path, and header.
-	// Valid 'kind's are String, Int, Float64, and Bool.
+	// Valid 'in's are query, path, header, and cookie.
+	// Valid 'kind's are String, Int, Int64, Uint, Float32, Float64, Bool, Slice, and Map.
+	// Items of a Slice and properties of a Map are converted according to the types in the schema.
+	// String values are then decoded according to the schema's format, if it has a ParamDecoder (see: SetParamDecoder).
+	// Missing parameters are set to the schema's default, if it has one.
 	Parameter(in, name, description string, required bool, schema interface{}, kind reflect.Kind) EndpointDeclaration
+	// Set the serialization style of a parameter, which must already be declared.
+	// Query and cookie parameters default to the form style with explode, and path and header parameters to the simple style.
+	ParameterStyle(in, name, style string, explode bool) EndpointDeclaration
 	// Attach a request body doc.
 	// `schema` will be used in the documentation, and `object` will be used for reading the body automatically.
+	// If `schema` is nil, it will be generated from the type of `object`.
+	// Missing properties are set to their default values from the schema before validation.
 	RequestBody(description string, required bool, schema interface{}, object interface{}) EndpointDeclaration
+	// Attach a request body doc for the given content type. May be called once for each accepted content type.
+	// The description is that of the first call which has one, and every call must agree on whether the body is required.
+	// Requests are read according to their Content-Type, and those with an undeclared content type are rejected.
+	//   - JSON and text/plain bodies are validated against the schema and read into `object`.
+	//   - Form bodies are converted into JSON objects before being validated and read into `object`.
+	//     Files in multipart forms are available through Data.Files, and their temporary files are removed once the request has been handled.
+	//   - application/octet-stream bodies are not validated, and are read as []byte.
+	// If `object` is nil, the body is read into a generic value instead.
+	RequestBodyContent(mimeType, description string, required bool, schema interface{}, object interface{}) EndpointDeclaration
 	// Attach a response doc. Schema may be nil.
 	Response(code int, description string, schema interface{}) EndpointDeclaration
+	// Attach a response doc for a range of status codes, where class is the first digit of the codes. (4 for 4XX)
+	// Responses with codes in the range which are not declared individually are validated against its schema.
+	// Since oasm.Responses cannot hold ranges, they are not part of Doc(), and are kept by the spec when the endpoint
+	// is defined, to be added to the served spec, its exports, and its validation.
+	ResponseRange(class int, description string, schema interface{}) EndpointDeclaration
+	// Attach the default response doc, which applies to all undeclared status codes and ranges. Schema may be nil.
+	DefaultResponse(description string, schema interface{}) EndpointDeclaration
+	// Attach a response doc with a schema generated from the type of `object`.
+	ResponseOf(code int, description string, object interface{}) EndpointDeclaration
+	// Set the media types that a response may be sent as, the first being the default.
+	// Each media type must have an encoder set on the OpenAPI, and uses the schema of the response.
+	// Responses are sent as application/json unless declared otherwise.
+	ResponseMediaTypes(code int, mimeTypes ...string) EndpointDeclaration
+	// Attach a header doc to the response for the given status code. The response must already be declared.
+	// Required headers which are missing from Response.Headers will be reported after the response is sent.
+	ResponseHeader(code int, name, description string, required bool, schema interface{}) EndpointDeclaration
+	// Map errors returned by the handler which match the target (using errors.Is) to responses with the status code.
+	// Mappings of the endpoint are checked before those of the OpenAPI. See: OpenAPI.MapError
+	MapError(target error, status int, description string, body ErrorBodyFunc) EndpointDeclaration
+	// Map errors returned by the handler which match the type of the target (using errors.As) to responses with the status code.
+	// Mappings of the endpoint are checked before those of the OpenAPI. See: OpenAPI.MapErrorType
+	MapErrorType(target interface{}, status int, description string, body ErrorBodyFunc) EndpointDeclaration
+	// Set the response validation mode of this endpoint, and the fraction of responses (from 0 to 1) which are validated.
+	// Overrides the settings of the OpenAPI. See: OpenAPI.SetResponseValidation
+	ResponseValidation(mode ResponseValidationMode, sampleRate float64) EndpointDeclaration
 	// Deprecate this endpoint.
 	Deprecate(comment string) EndpointDeclaration
-	// Attach a security doc.
+	// Attach a security doc, which is a security requirement of the schemes mapped to their required scopes.
+	// Requests must satisfy all schemes of any one requirement, using their security handlers. See: OpenAPI.SetSecurityHandler
 	Security(nameToScopesMapping map[string][]string) EndpointDeclaration
+	// Document that this endpoint requires no security, overriding the security requirements of the OpenAPI.
+	NoSecurity() EndpointDeclaration
+	// Add middleware which runs after the request has been authenticated, parsed, and validated.
+	// Middleware of the endpoint is run inside of the middleware of the OpenAPI, in the order it was added,
+	// so the first middleware added is the first to receive the Data and the last to receive the returned value.
+	// Middleware may respond without calling the next function to stop the chain. See: OpenAPI.Use
+	Use(middleware ...Middleware) EndpointDeclaration
 	// Attach a function to run when calling this endpoint.
 	// This should be the final function called when declaring an endpoint.
 	// This will also create a large amount of metadata to be used when parsing a request.
+	// Returns an error if a parameter of the path is not documented, or if the route conflicts with the route of
+	// another endpoint with the same method: by having the same path, by matching the same requests without either
+	// route taking precedence, or by taking precedence over every request that the other route matches.
 	Define(f HandlerFunc) (Endpoint, error)
 	// See: Define(f HandlerFunc) (Endpoint, error)
 	// Panics if an error occurs.
@@ -67,6 +119,8 @@ type endpointObject struct {
 	method      string
 	version     int
 	regexPath   *regexp.Regexp
+	// The regular expressions of the path parameters which have them, mapped by parameter name.
+	pathPatterns map[string]*regexp.Regexp
 
 	options             map[string]interface{}
 	userDefinedFunc     HandlerFunc
@@ -74,21 +128,29 @@ type endpointObject struct {
 	spec                *openAPI
 	parsedPath          map[string]int
 
-	bodyType       reflect.Type
-	bodyJsonSchema json.RawMessage
+	bodies map[string]*requestBodyMedia
 
 	query   []typedParameter
 	params  map[int]typedParameter
 	headers []typedParameter
+	cookies []typedParameter
 
 	reqSchemaName      string
 	responseSchemaRefs map[int]string
-}
-
-type typedParameter struct {
-	kind       reflect.Kind
-	jsonSchema json.RawMessage
-	oasm.Parameter
+	responseHeaders    map[int][]string
+	responseMediaTypes map[int][]string
+	errorMappings      []errorMapping
+	responseValidation *responseValidation
+	middleware         []Middleware
+
+	responseRanges           map[int]oasm.Response
+	responseRangeSchemaRefs  map[int]string
+	defaultResponseSchemaRef string
+
+	// Whether the operation declares its own security requirements, which may be empty.
+	securityDeclared bool
+	// Whether security handlers were set when the endpoint was defined, so that its requirements are enforced.
+	enforceSecurity bool
 }
 
 func (e *endpointObject) Version(version int) EndpointDeclaration {
@@ -116,13 +178,21 @@ func (e *endpointObject) Parameter(in, name, description string, required bool,
 		Required:    required,
 		Schema:      schema,
 	}
-	if kind != reflect.String && kind != reflect.Int && kind != reflect.Float64 && kind != reflect.Bool {
+	switch kind {
+	case reflect.String, reflect.Int, reflect.Int64, reflect.Uint, reflect.Float32, reflect.Float64, reflect.Bool,
+		reflect.Slice, reflect.Map:
+	default:
 		e.err = errors.New(
 			fmt.Sprintf("invalid kind for parameter %s in %s: ", name, in) +
-				"kind should be one of String, Int, Float64, Bool")
+				"kind should be one of String, Int, Int64, Uint, Float32, Float64, Bool, Slice, Map")
+		return e
+	}
+	styles, ok := parameterStyles[in]
+	if !ok {
+		e.err = errors.New(fmt.Sprintf("invalid location for parameter %s: %s", name, in))
 		return e
 	}
-	t := typedParameter{kind, nil, param}
+	t := typedParameter{kind: kind, style: styles[0], explode: styles[0] == StyleForm, Parameter: param}
 
 	// Handle jsonschema and swagger schemas including references.
 	b, err := json.Marshal(schema)
@@ -132,6 +202,22 @@ func (e *endpointObject) Parameter(in, name, description string, required bool,
 	}
 	t.jsonSchema = b
 	param.Schema = json.RawMessage(vjsonschema.SchemaRefReplace(b, refNameToSwaggerRef))
+	param.Style = t.style
+	param.Explode = t.explode
+
+	// Resolve the schemas used for converting items and properties.
+	t.schema = e.spec.resolveSchema(json.RawMessage(b))
+	if items, ok := t.schema["items"]; ok {
+		t.schema["items"] = e.spec.resolveSchema(items)
+	}
+	if additional, ok := t.schema["additionalProperties"]; ok {
+		t.schema["additionalProperties"] = e.spec.resolveSchema(additional)
+	}
+	if properties, ok := t.schema["properties"].(map[string]interface{}); ok {
+		for k, v := range properties {
+			properties[k] = e.spec.resolveSchema(v)
+		}
+	}
 	e.doc.Parameters = append(e.doc.Parameters, param)
 
 	// Handle go-type of the parameter
@@ -141,67 +227,136 @@ func (e *endpointObject) Parameter(in, name, description string, required bool,
 	case oasm.InPath:
 		loc, ok := e.parsedPath[name]
 		if !ok {
-			e.printError(errors.New("path parameter provided in docs, but not provided in route"))
-		} else {
-			e.params[loc] = t
+			e.err = errors.New(fmt.Sprintf("path parameter %s is documented, but is not in the path of %s: %s",
+				name, e.doc.OperationId, e.path))
+			return e
 		}
+		e.params[loc] = t
 	case oasm.InHeader:
 		e.headers = append(e.headers, t)
+	case oasm.InCookie:
+		e.cookies = append(e.cookies, t)
 	}
 	return e
 }
 
 func (e *endpointObject) RequestBody(description string, required bool, schema, object interface{}) EndpointDeclaration {
-	// Handle jsonschema and swagger schemas including references.
-	b, err := json.Marshal(schema)
+	return e.RequestBodyContent(oasm.MimeJson, description, required, schema, object)
+}
+
+func (e *endpointObject) Response(code int, description string, schema interface{}) EndpointDeclaration {
+	r, schemaRef, err := e.newResponse(fmt.Sprint(code), description, schema)
 	if err != nil {
-		e.err = errors.WithMessage(err, "failed to marshal request body schema: "+e.doc.OperationId)
+		e.err = err
 		return e
 	}
-	e.bodyJsonSchema = b
-	schema = json.RawMessage(vjsonschema.SchemaRefReplace(b, refNameToSwaggerRef))
-
-	e.bodyType = reflect.TypeOf(object)
-	e.doc.RequestBody = &oasm.RequestBody{
-		Description: description,
-		Required:    required,
-		Content: oasm.MediaTypesMap{
-			oasm.MimeJson: {
-				Schema: schema,
-			},
-		},
+	if schemaRef != "" {
+		e.responseSchemaRefs[code] = schemaRef
 	}
+	e.doc.Responses.Codes[code] = r
 	return e
 }
 
-func (e *endpointObject) Response(code int, description string, schema interface{}) EndpointDeclaration {
+// Create a response doc for the status key, returning the name of its schema if it has one.
+func (e *endpointObject) newResponse(key, description string, schema interface{}) (oasm.Response, string, error) {
 	r := oasm.Response{
 		Description: description,
 	}
+	if schema == nil {
+		return r, "", nil
+	}
 
-	if schema != nil {
-		jsonSchemaRef := fmt.Sprint("endpoint_", e.doc.OperationId, "_response_", code)
-		e.responseSchemaRefs[code] = jsonSchemaRef
+	jsonSchemaRef := fmt.Sprint("endpoint_", e.doc.OperationId, "_response_", key)
 
-		// Handle jsonschema and swagger schemas including references.
-		b, err := json.Marshal(schema)
-		if err != nil {
-			e.err = errors.WithMessage(err, "failed to marshal response schema: "+fmt.Sprintf(e.doc.OperationId, code))
+	// Handle jsonschema and swagger schemas including references.
+	b, err := json.Marshal(schema)
+	if err != nil {
+		return r, "", errors.WithMessage(err, "failed to marshal response schema: "+fmt.Sprint(e.doc.OperationId, " ", key))
+	}
+	if schema, err = docSchema(b); err != nil {
+		return r, "", errors.WithMessage(err, "failed to convert response schema: "+fmt.Sprint(e.doc.OperationId, " ", key))
+	}
+	if err := e.spec.validatorBuilder.AddSchema(jsonSchemaRef, b); err != nil {
+		return r, "", errors.WithMessage(err, "failed to add response schema: "+fmt.Sprint(e.doc.OperationId, " ", key))
+	}
+
+	r.Content = oasm.MediaTypesMap{
+		oasm.MimeJson: {
+			Schema: schema,
+		},
+	}
+	return r, jsonSchemaRef, nil
+}
+
+func (e *endpointObject) ResponseOf(code int, description string, object interface{}) EndpointDeclaration {
+	schema, err := e.spec.SchemaOf(object)
+	if err != nil {
+		e.err = errors.WithMessage(err, "failed to generate response schema: "+fmt.Sprint(e.doc.OperationId, " ", code))
+		return e
+	}
+	return e.Response(code, description, schema)
+}
+
+func (e *endpointObject) ResponseMediaTypes(code int, mimeTypes ...string) EndpointDeclaration {
+	r, ok := e.doc.Responses.Codes[code]
+	if !ok {
+		e.err = errors.New(fmt.Sprint("response media types declared before their response: ", e.doc.OperationId, " ", code))
+		return e
+	}
+	if len(mimeTypes) == 0 {
+		e.err = errors.New(fmt.Sprint("no response media types provided: ", e.doc.OperationId, " ", code))
+		return e
+	}
+
+	var schema interface{}
+	for _, media := range r.Content {
+		schema = media.Schema
+	}
+	r.Content = make(oasm.MediaTypesMap)
+	for _, mimeType := range mimeTypes {
+		if _, ok := e.spec.encoders[mimeType]; !ok {
+			e.err = errors.New(fmt.Sprint("no response encoder for media type: ", e.doc.OperationId, " ", code, " ", mimeType))
 			return e
 		}
-		schema = json.RawMessage(vjsonschema.SchemaRefReplace(b, refNameToSwaggerRef))
-		if err := e.spec.validatorBuilder.AddSchema(jsonSchemaRef, b); err != nil {
-			e.err = errors.WithMessage(err, "failed to add response schema: "+fmt.Sprint(e.doc.OperationId, " ", code))
-			return e
+		r.Content[mimeType] = oasm.MediaType{
+			Schema: schema,
 		}
+	}
+	e.doc.Responses.Codes[code] = r
+	e.responseMediaTypes[code] = mimeTypes
+	return e
+}
 
-		r.Content = oasm.MediaTypesMap{
-			oasm.MimeJson: {
-				Schema: schema,
-			},
-		}
+func (e *endpointObject) ResponseHeader(code int, name, description string, required bool, schema interface{}) EndpointDeclaration {
+	r, ok := e.doc.Responses.Codes[code]
+	if !ok {
+		e.err = errors.New(fmt.Sprint("response header declared before its response: ", e.doc.OperationId, " ", code, " ", name))
+		return e
+	}
+
+	// Handle jsonschema and swagger schemas including references.
+	b, err := json.Marshal(schema)
+	if err != nil {
+		e.err = errors.WithMessage(err, "failed to marshal response header schema: "+fmt.Sprint(e.doc.OperationId, " ", code, " ", name))
+		return e
+	}
+	if schema, err = docSchema(b); err != nil {
+		e.err = errors.WithMessage(err, "failed to convert response header schema: "+fmt.Sprint(e.doc.OperationId, " ", code, " ", name))
+		return e
+	}
+
+	if r.Headers == nil {
+		r.Headers = make(map[string]oasm.Header)
+	}
+	r.Headers[name] = oasm.Header{
+		Description: description,
+		Required:    required,
+		Schema:      schema,
 	}
 	e.doc.Responses.Codes[code] = r
+	if required {
+		e.responseHeaders[code] = append(e.responseHeaders[code], name)
+	}
 	return e
 }
 
@@ -215,6 +370,13 @@ func (e *endpointObject) Deprecate(comment string) EndpointDeclaration {
 
 func (e *endpointObject) Security(nameToScopesMapping map[string][]string) EndpointDeclaration {
 	e.doc.Security = append(e.doc.Security, nameToScopesMapping)
+	e.securityDeclared = true
+	return e
+}
+
+func (e *endpointObject) NoSecurity() EndpointDeclaration {
+	e.doc.Security = []oasm.SecurityRequirement{}
+	e.securityDeclared = true
 	return e
 }
 
@@ -233,6 +395,15 @@ func (e *endpointObject) Define(f HandlerFunc) (Endpoint, error) {
 	var err error
 
 	spec := e.spec
+	if err = e.checkPathParams(); err != nil {
+		return nil, err
+	}
+	if err = spec.checkRoute(e); err != nil {
+		return nil, err
+	}
+	if err = e.checkSecurityHandlers(); err != nil {
+		return nil, err
+	}
 
 	e.userDefinedFunc = f
 
@@ -252,6 +423,11 @@ func (e *endpointObject) Define(f HandlerFunc) (Endpoint, error) {
 		"required":   make([]string, 0, 3),
 		"properties": make(map[string]interface{}),
 	}
+	cookiesSchema := map[string]interface{}{
+		"type":       "object",
+		"required":   make([]string, 0, 3),
+		"properties": make(map[string]interface{}),
+	}
 
 	dataSchema := map[string]interface{}{
 		"type": "object",
@@ -259,21 +435,20 @@ func (e *endpointObject) Define(f HandlerFunc) (Endpoint, error) {
 			"Query",
 			"Params",
 			"Headers",
+			"Cookies",
 		},
 		"properties": map[string]interface{}{
 			"Query":   querySchema,
 			"Params":  paramsSchema,
 			"Headers": headersSchema,
+			"Cookies": cookiesSchema,
 		},
 	}
 
-	// Create schema for request body.
+	// A missing request body is validated using the data schema without a Body.
 	doc := e.Doc()
-	if doc.RequestBody != nil {
-		dataSchema["properties"].(map[string]interface{})["Body"] = e.bodyJsonSchema
-		if doc.RequestBody.Required {
-			dataSchema["required"] = append(dataSchema["required"].([]string), "Body")
-		}
+	if doc.RequestBody != nil && doc.RequestBody.Required {
+		dataSchema["required"] = append(dataSchema["required"].([]string), "Body")
 	}
 
 	// Create schema for a single parameter:
@@ -294,6 +469,9 @@ func (e *endpointObject) Define(f HandlerFunc) (Endpoint, error) {
 	for _, p := range e.headers {
 		addToSchema(&headersSchema, p)
 	}
+	for _, p := range e.cookies {
+		addToSchema(&cookiesSchema, p)
+	}
 
 	// Save the name of the schema for use in validations.
 	dataSchemaBytes, err := json.Marshal(dataSchema)
@@ -303,9 +481,38 @@ func (e *endpointObject) Define(f HandlerFunc) (Endpoint, error) {
 	if err = e.spec.validatorBuilder.AddSchema(e.reqSchemaName, dataSchemaBytes); err != nil {
 		return nil, errors.WithMessage(err, "failed to add/parse data schema for: "+e.doc.OperationId)
 	}
+
+	// Create a data schema with a Body for each content type of the request body.
+	for _, media := range e.bodies {
+		properties := make(map[string]interface{})
+		for k, v := range dataSchema["properties"].(map[string]interface{}) {
+			properties[k] = v
+		}
+		properties["Body"] = media.jsonSchema
+		if media.jsonSchema == nil {
+			properties["Body"] = map[string]interface{}{}
+		}
+		mediaSchema := map[string]interface{}{
+			"type":       "object",
+			"required":   dataSchema["required"],
+			"properties": properties,
+		}
+		mediaSchemaBytes, err := json.Marshal(mediaSchema)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to marshal data schema for: "+e.doc.OperationId+" "+media.mimeType)
+		}
+		if err = e.spec.validatorBuilder.AddSchema(media.schemaName, mediaSchemaBytes); err != nil {
+			return nil, errors.WithMessage(err, "failed to add/parse data schema for: "+e.doc.OperationId+" "+media.mimeType)
+		}
+	}
 	if err = e.spec.buildValidator(); err != nil {
 		return nil, err
 	}
+	e.documentErrorMappings()
+	e.documentSecurity()
+	if err = e.documentProblems(); err != nil {
+		return nil, errors.WithMessage(err, "failed to document problem responses for: "+e.doc.OperationId)
+	}
 
 	// Create routes and docs for all endpoints
 	pathItem, ok := spec.doc.Paths[e.swaggerPath]
@@ -315,11 +522,19 @@ func (e *endpointObject) Define(f HandlerFunc) (Endpoint, error) {
 		spec.doc.Paths[e.swaggerPath] = pathItem
 	}
 	pathItem.Methods[e.method] = *doc
-	spec.routeCreator(e, http.HandlerFunc(e.Call))
+	spec.documentResponseRanges(e.swaggerPath, e.method, e.responseRanges)
+	spec.docChanged()
+	if err = spec.addRoute(e); err != nil {
+		return nil, errors.WithMessage(err, "failed to add the route for: "+e.doc.OperationId)
+	}
+	if spec.routeCreator != nil {
+		spec.routeCreator(e, http.HandlerFunc(e.Call))
+	}
 	return e, nil
 }
 
 func (e *endpointObject) Doc() *oasm.Operation {
+	e.spec.docChanged()
 	return &e.doc
 }
 
@@ -337,7 +552,7 @@ func (e *endpointObject) RegexPath() *regexp.Regexp {
 
 func (e *endpointObject) SecurityMapping() []map[string]oasm.SecurityScheme {
 	schemes := make([]map[string]oasm.SecurityScheme, 0, 2)
-	if e.spec.doc.Security != nil {
+	if e.spec.doc.Security != nil && !e.securityDeclared {
 		for _, s := range e.spec.doc.Security {
 			m := make(map[string]oasm.SecurityScheme)
 			for name := range s {
@@ -359,14 +574,7 @@ func (e *endpointObject) SecurityMapping() []map[string]oasm.SecurityScheme {
 }
 
 func (e *endpointObject) UserDefinedFunc(d Data) (i interface{}, err error) {
-	defer func() {
-		panicErr := recover()
-		if panicErr != nil {
-			err = fmt.Errorf("a fatal error occurred: %v", panicErr)
-			log.Printf("endpoint panic (%s %s): %s\n", e.method, e.swaggerPath, panicErr)
-			debug.PrintStack()
-		}
-	}()
+	defer e.recoverPanic(&err)
 	if e.userDefinedFunc != nil {
 		return e.userDefinedFunc(d)
 	}
@@ -375,33 +583,38 @@ func (e *endpointObject) UserDefinedFunc(d Data) (i interface{}, err error) {
 
 func (e *endpointObject) Call(w http.ResponseWriter, r *http.Request) {
 	var (
-		data   = NewData(w, r, e)
-		output interface{}
-		res    Response
+		data    = NewData(w, r, e)
+		output  interface{}
+		res     Response
+		handled bool
 	)
 
-	endpointError := e.parseRequest(&data)
+	endpointError := e.authenticate(&data)
+	if endpointError == nil {
+		endpointError = e.parseRequest(&data)
+		defer e.removeFormFiles(&data)
+	}
 	if endpointError == nil {
-		output, endpointError = e.UserDefinedFunc(data)
+		output, endpointError = e.handle(data)
+		handled = true
 	}
 
 	if endpointError != nil {
-		if valErr, ok := endpointError.(jsonValidationError); ok {
-			res = Response{
-				Body:   valErr,
-				Status: 400,
-			}
-			endpointError = nil
-		} else if malErr, ok := endpointError.(malformedJSONError); ok {
-			res = Response{
-				Body:   malErr,
-				Status: 400,
+		// Mapped and expected errors are only reported when they are server errors.
+		// Only errors from the handler are mapped, so that authentication and request errors keep their statuses.
+		if mapped, ok := e.mapError(endpointError); ok && handled {
+			res = mapped
+			if res.Status < 500 {
+				endpointError = nil
 			}
-			endpointError = nil
 		} else {
+			problem, expected := errorToProblem(endpointError)
 			res = Response{
-				Body:   "Internal Server Error",
-				Status: 500,
+				Body:   problem,
+				Status: problem.Status,
+			}
+			if expected && problem.Status < 500 {
+				endpointError = nil
 			}
 		}
 	} else if response, ok := output.(Response); ok {
@@ -417,11 +630,35 @@ func (e *endpointObject) Call(w http.ResponseWriter, r *http.Request) {
 		res.Status = 200
 	}
 
+	if e.checkStatusDeclared(data, res) {
+		problem := NewProblem(500, "The response status is not declared.")
+		res = Response{
+			Body:   problem,
+			Status: problem.Status,
+		}
+	}
+
+	// Validate the response body before it is sent, so that invalid responses can be replaced.
+	var validationFailure *ResponseValidationFailure
+	validation := e.responseValidationSettings()
+	if validation.sample() {
+		validationFailure = e.validateResponse(res)
+		if validationFailure != nil && validation.mode == ResponseValidationFail {
+			problem := NewProblem(500, "The response failed validation.")
+			res = Response{
+				Body:   problem,
+				Status: problem.Status,
+			}
+		}
+	}
+
+	for name, value := range res.Headers {
+		w.Header().Set(name, value)
+	}
+
 	if res.Body == nil {
 		w.WriteHeader(res.Status)
 	} else {
-		var b []byte
-		var err error
 		indent := e.spec.jsonIndent
 		h := r.Header.Get(JSONIndentHeader)
 		if h != "" {
@@ -433,40 +670,52 @@ func (e *endpointObject) Call(w http.ResponseWriter, r *http.Request) {
 				indent = i
 			}
 		}
-		if indent > 0 {
-			b, err = json.MarshalIndent(res.Body, "", strings.Repeat(" ", indent))
+
+		// Problems are always sent as problem+json, regardless of the media types which are accepted.
+		var mimeType string
+		var b []byte
+		var err error
+		if isProblem(res.Body) {
+			mimeType = MimeProblemJson
+			b, err = EncodeJSON(res.Body, indent)
 		} else {
-			b, err = json.Marshal(res.Body)
+			mimeTypes := e.mediaTypes(res.Status)
+			mimeType, b, err = e.encodeBody(res.Body, indent, negotiateMediaTypes(r.Header.Get("Accept"), mimeTypes))
+			if mimeType == "" {
+				problem := newNotAcceptableError(r.Header.Get("Accept"), mimeTypes)
+				res = Response{
+					Body:   problem,
+					Status: problem.Status,
+				}
+				mimeType = MimeProblemJson
+				b, err = EncodeJSON(res.Body, indent)
+			}
 		}
 		if err != nil {
-			e.printError(errors.WithMessagef(err, "failed to marshal response body (%v)", res.Body))
+			e.printError(errors.WithMessagef(err, "failed to encode response body as %s (%v)", mimeType, res.Body))
 			res.Status = 500
 			b = []byte("Internal Server Error")
+			mimeType = MimeTextPlain
 		}
 
-		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Type", mimeType)
 		w.WriteHeader(res.Status)
 		if _, err = w.Write(b); err != nil {
 			e.printError(errors.WithMessage(err, "error occurred while writing the response body"))
 		}
 	}
 
-	// Validate response body
-	if schema, ok := e.responseSchemaRefs[res.Status]; ok {
-		bodyBytes, err := json.Marshal(res.Body)
-		if err != nil {
-			e.printError(errors.WithMessage(err, "failed to marshal response body"))
-		} else {
-			result, err := e.spec.validator.Validate(schema, bodyBytes)
-			if err != nil {
-				e.printError(errors.WithMessage(err, "response body contains malformed json"))
-			} else if !result.Valid() {
-				e.printError(errors.WithMessagef(
-					newJSONValidationError(result), "response body failed validation for status %v", res.Status))
-			}
+	// Validate response headers
+	for _, name := range e.responseHeaders[res.Status] {
+		if w.Header().Get(name) == "" {
+			e.printError(fmt.Errorf("response is missing required header %s for status %v", name, res.Status))
 		}
 	}
 
+	if validationFailure != nil {
+		e.reportResponseValidationFailure(data, *validationFailure)
+	}
+
 	if e.spec.responseAndErrorHandler != nil {
 		e.spec.responseAndErrorHandler(data, res, endpointError)
 	} else {
@@ -474,69 +723,89 @@ func (e *endpointObject) Call(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// Get the media types that a response with the status code may be sent as.
+func (e *endpointObject) mediaTypes(status int) []string {
+	if mimeTypes, ok := e.responseMediaTypes[status]; ok {
+		return mimeTypes
+	}
+	return []string{oasm.MimeJson}
+}
+
+// Encode the body as the first of the media types which can represent it.
+// The media type is empty if none of them can.
+func (e *endpointObject) encodeBody(body interface{}, indent int, mimeTypes []string) (string, []byte, error) {
+	for _, mimeType := range mimeTypes {
+		encoder, ok := e.spec.encoders[mimeType]
+		if !ok {
+			encoder = EncodeJSON
+		}
+		b, err := encoder(body, indent)
+		if !errors.Is(err, ErrUnsupportedBody) {
+			return mimeType, b, err
+		}
+	}
+	return "", nil, nil
+}
+
+// Remove the temporary files of the multipart form of the request, once the request has been handled.
+func (e *endpointObject) removeFormFiles(data *Data) {
+	if data.form == nil {
+		return
+	}
+	if err := data.form.RemoveAll(); err != nil {
+		e.printError(errors.WithMessage(err, "failed to remove the files of the multipart form"))
+	}
+}
+
 func (e *endpointObject) parseRequest(data *Data) error {
 	var err error
 	var requestBody []byte
+	var bodyJSON json.RawMessage
+	var media *requestBodyMedia
 
-	convertParamType := func(param typedParameter, item string) (interface{}, error) {
-		switch param.kind {
-		case reflect.String:
-			return item, nil
-		case reflect.Int:
-			if i, err := strconv.Atoi(item); err != nil {
-				return nil, newParameterTypeError(param.Parameter, "int", item)
-			} else {
-				return i, nil
+	if len(e.bodies) > 0 {
+		body, err := e.openRequestBody(data)
+		if err != nil {
+			return err
+		}
+		if body != nil {
+			if media, err = e.requestMedia(data); err != nil {
+				return err
 			}
-		case reflect.Float64:
-			if i, err := strconv.ParseFloat(item, 64); err != nil {
-				return nil, newParameterTypeError(param.Parameter, "float", item)
-			} else {
-				return i, nil
+			if requestBody, bodyJSON, err = e.requestBodyToJSON(data, media, body); err != nil {
+				return err
 			}
-		case reflect.Bool:
-			if i, err := strconv.ParseBool(item); err != nil {
-				return nil, newParameterTypeError(param.Parameter, "bool", item)
-			} else {
-				return i, nil
+			if media.jsonSchema != nil {
+				if bodyJSON, err = e.spec.applyBodyDefaults(media.jsonSchema, bodyJSON); err != nil {
+					return err
+				}
 			}
-		default:
-			return nil, errors.New("bad reflection type for converting parameter from string")
-		}
-	}
-
-	if e.bodyType != nil {
-		requestBody, err = ioutil.ReadAll(data.Req.Body)
-		if err != nil {
-			return errors.WithMessage(err, "failed to read request body")
-		}
-		err = data.Req.Body.Close()
-		if err != nil {
-			return errors.WithMessage(err, "failed to close request body")
 		}
 	}
 
 	if len(e.query) > 0 {
-		getQueryParam := data.Req.URL.Query().Get
+		query := data.Req.URL.Query()
 		for _, param := range e.query {
 			name := param.Name
-			query := getQueryParam(name)
-			if query == "" {
-				continue
-			}
-			data.Query[name], err = convertParamType(param, query)
+			value, ok, err := param.fromQuery(query)
 			if err != nil {
 				return errors.WithMessage(err, "failed to convert query parameter "+name)
 			}
+			if ok {
+				data.Query[name] = value
+			} else if value, ok = param.defaultValue(); ok {
+				data.Query[name] = value
+			}
 		}
 	}
 
 	if len(e.params) > 0 {
-		log.Println(e.regexPath.String(), e.params)
-		subMatches := e.regexPath.FindStringSubmatch(data.Req.URL.Path)
-		log.Println(e.path, subMatches, e.regexPath.String(), data.Req.URL.Path)
+		values, ok := e.rawPathParams(data.Req)
+		if !ok {
+			return NewProblem(404, "The path does not match the endpoint: "+data.Req.URL.Path)
+		}
 		for loc, param := range e.params {
-			data.Params[param.Name], err = convertParamType(param, subMatches[loc])
+			data.Params[param.Name], err = param.fromString(values[loc])
 			if err != nil {
 				return errors.WithMessage(err, "failed to convert path parameter "+param.Name)
 			}
@@ -549,28 +818,51 @@ func (e *endpointObject) parseRequest(data *Data) error {
 			name := param.Name
 			header := getHeader(name)
 			if header == "" {
+				if value, ok := param.defaultValue(); ok {
+					data.Headers[name] = value
+				}
 				continue
 			}
-			data.Headers[name], err = convertParamType(param, header)
+			data.Headers[name], err = param.fromString(header)
 			if err != nil {
 				return errors.WithMessage(err, "failed to convert header parameter "+name)
 			}
 		}
 	}
 
+	if len(e.cookies) > 0 {
+		for _, param := range e.cookies {
+			name := param.Name
+			cookie, err := data.Req.Cookie(name)
+			if err != nil || cookie.Value == "" {
+				if value, ok := param.defaultValue(); ok {
+					data.Cookies[name] = value
+				}
+				continue
+			}
+			data.Cookies[name], err = param.fromString(cookie.Value)
+			if err != nil {
+				return errors.WithMessage(err, "failed to convert cookie parameter "+name)
+			}
+		}
+	}
+
 	dataJson := map[string]interface{}{
 		"Query":   data.Query,
 		"Params":  data.Params,
 		"Headers": data.Headers,
+		"Cookies": data.Cookies,
 	}
-	if e.bodyType != nil {
-		dataJson["Body"] = json.RawMessage(requestBody)
+	schemaName := e.reqSchemaName
+	if media != nil {
+		dataJson["Body"] = bodyJSON
+		schemaName = media.schemaName
 	}
 	b, err := json.Marshal(dataJson)
 	if err != nil {
 		return newMalformedJSONError(err)
 	}
-	result, err := e.spec.validator.Validate(e.reqSchemaName, b)
+	result, err := e.spec.validator.Validate(schemaName, b)
 	if err != nil {
 		return newMalformedJSONError(err)
 	}
@@ -578,16 +870,26 @@ func (e *endpointObject) parseRequest(data *Data) error {
 		return newJSONValidationError(result)
 	}
 
-	if e.bodyType != nil {
-		data.Body = reflect.New(e.bodyType).Interface()
-		err = json.Unmarshal(requestBody, data.Body)
-		if err != nil {
-			return newMalformedJSONError(err)
-		}
+	if err = e.decodeParameters(data); err != nil {
+		return err
+	}
+	if media != nil {
+		return e.readRequestBody(data, media, requestBody, bodyJSON)
 	}
 	return nil
 }
 
+// Recover from a panic in an endpoint function, setting the error to report it.
+// This must be deferred directly.
+func (e *endpointObject) recoverPanic(err *error) {
+	panicErr := recover()
+	if panicErr != nil {
+		*err = fmt.Errorf("a fatal error occurred: %v", panicErr)
+		log.Printf("endpoint panic (%s %s): %s\n", e.method, e.swaggerPath, panicErr)
+		debug.PrintStack()
+	}
+}
+
 func (e *endpointObject) printError(err error) {
 	log.Printf("endpoint error (%s): %v\n", e.doc.OperationId, err)
 }
@@ -598,8 +900,10 @@ func (e *endpointObject) parsePath() {
 		parsedPath     = make(map[string]int)
 
 		pathComparison = ""
-		pathRegexStr   = e.spec.url.Path
+		pathRegexStr   = strings.TrimSuffix(e.spec.url.Path, "/")
 		pathParamIndex int
+		pathPatterns   = make(map[string]*regexp.Regexp)
+		patternErr     error
 	)
 	for _, subMatch := range pathRegex.FindAllStringSubmatch(e.path, -1) {
 		pathComparison += subMatch[0]
@@ -609,6 +913,12 @@ func (e *endpointObject) parsePath() {
 			parsedPath[subMatch[1]] = pathParamIndex
 			if subMatch[2] != "" {
 				pathRegexStr += "/(" + subMatch[2] + ")"
+				// Values which are read by a router must match the whole regular expression.
+				if pattern, err := regexp.Compile("^(?:" + subMatch[2] + ")$"); err == nil {
+					pathPatterns[subMatch[1]] = pattern
+				} else if patternErr == nil {
+					patternErr = err
+				}
 			} else {
 				pathRegexStr += "/([^/]+)"
 			}
@@ -618,6 +928,9 @@ func (e *endpointObject) parsePath() {
 		}
 	}
 	e.regexPath, e.err = regexp.Compile(pathRegexStr)
+	if e.err == nil && patternErr != nil {
+		e.err = patternErr
+	}
 	if pathComparison != e.path {
 		e.err = errors.New("endpoint path does not match the required format:\n" +
 			e.path + "\n" + pathComparison + "\n" +
@@ -625,4 +938,5 @@ func (e *endpointObject) parsePath() {
 	}
 	e.swaggerPath = newSwaggerPath
 	e.parsedPath = parsedPath
+	e.pathPatterns = pathPatterns
 }
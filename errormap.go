@@ -0,0 +1,138 @@
+package oas
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/oasm"
+	"reflect"
+)
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Creates the body of the response for an error which matched an error mapping.
+type ErrorBodyFunc func(err error, status int) interface{}
+
+// A mapping from errors returned by handlers to the responses sent for them.
+type errorMapping struct {
+	target      error
+	targetType  reflect.Type
+	status      int
+	description string
+	body        ErrorBodyFunc
+}
+
+// Create a mapping for a sentinel error, matched using errors.Is.
+func newErrorMapping(target error, status int, description string, body ErrorBodyFunc) (errorMapping, error) {
+	if target == nil {
+		return errorMapping{}, errors.New("cannot map a nil error")
+	}
+	return errorMapping{target: target, status: status, description: description, body: body}, nil
+}
+
+// Create a mapping for an error type, matched using errors.As.
+// The target is either a value of the error type, or a pointer to a value of the error type as with errors.As.
+func newErrorTypeMapping(target interface{}, status int, description string, body ErrorBodyFunc) (errorMapping, error) {
+	t := reflect.TypeOf(target)
+	switch {
+	case t == nil:
+		return errorMapping{}, errors.New("cannot map a nil error type")
+	case t.Implements(errorType):
+	case t.Kind() == reflect.Ptr && t.Elem().Implements(errorType):
+		t = t.Elem()
+	default:
+		return errorMapping{}, fmt.Errorf("error type target must implement error or be a pointer to a type which does, found %v", t)
+	}
+	return errorMapping{targetType: t, status: status, description: description, body: body}, nil
+}
+
+// Check if the error matches the mapping, returning the matched error.
+func (m errorMapping) match(err error) (error, bool) {
+	if m.target != nil {
+		return err, errors.Is(err, m.target)
+	}
+	target := reflect.New(m.targetType)
+	if !errors.As(err, target.Interface()) {
+		return nil, false
+	}
+	return target.Elem().Interface().(error), true
+}
+
+// Create the response for an error which matched the mapping.
+// By default, the response is a problem with the error message as its detail.
+func (m errorMapping) response(err error) Response {
+	if m.body != nil {
+		return Response{Status: m.status, Body: m.body(err, m.status)}
+	}
+	return Response{Status: m.status, Body: NewProblem(m.status, err.Error())}
+}
+
+// Find the response for an error using the mappings of the endpoint, then those of the spec.
+func (e *endpointObject) mapError(err error) (Response, bool) {
+	for _, mappings := range [][]errorMapping{e.errorMappings, e.spec.errorMappings} {
+		for _, m := range mappings {
+			if matched, ok := m.match(err); ok {
+				return m.response(matched), true
+			}
+		}
+	}
+	return Response{}, false
+}
+
+// Document the responses of mapped errors which have not been declared on the endpoint.
+// Responses with custom bodies are documented with an empty JSON schema, and others are documented as problems.
+func (e *endpointObject) documentErrorMappings() {
+	for _, mappings := range [][]errorMapping{e.errorMappings, e.spec.errorMappings} {
+		for _, m := range mappings {
+			if _, ok := e.doc.Responses.Codes[m.status]; ok {
+				continue
+			}
+			r := oasm.Response{Description: m.description}
+			if m.body != nil {
+				r.Content = oasm.MediaTypesMap{
+					oasm.MimeJson: {
+						Schema: map[string]interface{}{},
+					},
+				}
+			}
+			e.doc.Responses.Codes[m.status] = r
+		}
+	}
+}
+
+func (o *openAPI) MapError(target error, status int, description string, body ErrorBodyFunc) error {
+	m, err := newErrorMapping(target, status, description, body)
+	if err != nil {
+		return err
+	}
+	o.errorMappings = append(o.errorMappings, m)
+	return nil
+}
+
+func (o *openAPI) MapErrorType(target interface{}, status int, description string, body ErrorBodyFunc) error {
+	m, err := newErrorTypeMapping(target, status, description, body)
+	if err != nil {
+		return err
+	}
+	o.errorMappings = append(o.errorMappings, m)
+	return nil
+}
+
+func (e *endpointObject) MapError(target error, status int, description string, body ErrorBodyFunc) EndpointDeclaration {
+	m, err := newErrorMapping(target, status, description, body)
+	if err != nil {
+		e.err = errors.WithMessage(err, "failed to map error: "+e.doc.OperationId)
+		return e
+	}
+	e.errorMappings = append(e.errorMappings, m)
+	return e
+}
+
+func (e *endpointObject) MapErrorType(target interface{}, status int, description string, body ErrorBodyFunc) EndpointDeclaration {
+	m, err := newErrorTypeMapping(target, status, description, body)
+	if err != nil {
+		e.err = errors.WithMessage(err, "failed to map error type: "+e.doc.OperationId)
+		return e
+	}
+	e.errorMappings = append(e.errorMappings, m)
+	return e
+}
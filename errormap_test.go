@@ -0,0 +1,136 @@
+package oas
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tjbrockmeyer/oasm"
+)
+
+var (
+	errNotFound = errors.New("not found")
+	errGone     = errors.New("gone")
+)
+
+type conflictError struct{ id string }
+
+func (e conflictError) Error() string { return "conflict: " + e.id }
+
+type quotaError struct{ limit int }
+
+func (e *quotaError) Error() string { return fmt.Sprintf("quota of %v exceeded", e.limit) }
+
+// Create a spec with error mappings, and an endpoint which returns the error.
+func errorMappingSpec(t *testing.T, err error) OpenAPI {
+	t.Helper()
+	spec := newTestSpec(t)
+	mustMap := func(err error) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustMap(spec.MapError(errNotFound, 404, "Not found.", nil))
+	mustMap(spec.MapError(errGone, 400, "Bad request.", nil))
+	mustMap(spec.MapErrorType(conflictError{}, 409, "Conflict.", nil))
+	mustMap(spec.MapErrorType(new(*quotaError), 429, "Too many requests.", func(err error, _ int) interface{} {
+		return map[string]int{"limit": err.(*quotaError).limit}
+	}))
+	spec.NewEndpoint("get", "GET", "/items", "", "", nil).
+		Parameter(oasm.InQuery, "page", "", false, map[string]interface{}{"type": "integer"}, reflect.Int).
+		MapError(errGone, 410, "Gone.", nil).
+		MustDefine(func(Data) (interface{}, error) { return nil, err })
+	return spec
+}
+
+func TestErrorMappings(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		target   string
+		status   int
+		contains string
+	}{
+		{"sentinel", errNotFound, "/api/items", 404, `"detail": "not found"`},
+		{"wrapped sentinel", fmt.Errorf("loading item: %w", errNotFound), "/api/items", 404, `"detail": "loading item: not found"`},
+		{"value type", fmt.Errorf("saving: %w", conflictError{"a"}), "/api/items", 409, `"detail": "conflict: a"`},
+		{"pointer type with a body", &quotaError{3}, "/api/items", 429, `"limit": 3`},
+		{"endpoint mapping first", errGone, "/api/items", 410, `"detail": "gone"`},
+		{"unmapped", errors.New("boom"), "/api/items", 500, `"status": 500`},
+		{"request errors are not mapped", errNotFound, "/api/items?page=x", 400, `"status": 400`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(errorMappingSpec(t, tt.err), "GET", tt.target, nil, nil)
+			assertStatus(t, w, tt.status)
+			if !strings.Contains(w.Body.String(), tt.contains) {
+				t.Fatalf("expected the body to contain %s: %s", tt.contains, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestErrorMappingsSkipAuthentication(t *testing.T) {
+	spec := apiKeySpec(t)
+	// Every error matches a mapping for the error interface, but authentication errors must keep their status.
+	if err := spec.MapErrorType(new(error), 418, "Anything.", nil); err != nil {
+		t.Fatal(err)
+	}
+	spec.NewEndpoint("get", "GET", "/items", "", "", nil).
+		MustDefine(func(Data) (interface{}, error) { return nil, errors.New("boom") })
+
+	assertStatus(t, serve(spec, "GET", "/api/items", nil, nil), 401)
+	assertStatus(t, serve(spec, "GET", "/api/items", nil, map[string]string{"X-Key": "wrong"}), 401)
+	assertStatus(t, serve(spec, "GET", "/api/items", nil, map[string]string{"X-Key": "secret"}), 418)
+}
+
+func TestErrorMappingTargets(t *testing.T) {
+	tests := []struct {
+		name   string
+		target interface{}
+		valid  bool
+	}{
+		{"value type", conflictError{}, true},
+		{"pointer to value type", new(conflictError), true},
+		{"pointer type", (*quotaError)(nil), true},
+		{"pointer to pointer type", new(*quotaError), true},
+		{"interface", new(error), true},
+		{"nil", nil, false},
+		{"not an error", "text", false},
+		{"pointer to not an error", new(int), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newTestSpec(t).MapErrorType(tt.target, 400, "", nil)
+			if (err == nil) != tt.valid {
+				t.Fatalf("expected valid to be %v, got error: %v", tt.valid, err)
+			}
+		})
+	}
+	if err := newTestSpec(t).MapError(nil, 400, "", nil); err == nil {
+		t.Fatal("expected an error for a nil target")
+	}
+}
+
+func TestDocumentErrorMappings(t *testing.T) {
+	spec := newTestSpec(t)
+	if err := spec.MapError(errNotFound, 404, "Not found.", nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := spec.MapError(errGone, 410, "Gone.", func(error, int) interface{} { return "gone" }); err != nil {
+		t.Fatal(err)
+	}
+	e := spec.NewEndpoint("get", "GET", "/items", "", "", nil).
+		Response(404, "Missing item.", nil).
+		MustDefine(func(Data) (interface{}, error) { return nil, nil })
+
+	codes := e.Doc().Responses.Codes
+	if codes[404].Description != "Missing item." {
+		t.Fatalf("expected the declared 404 response to be kept, got %+v", codes[404])
+	}
+	if codes[410].Description != "Gone." || codes[410].Content[oasm.MimeJson].Schema == nil {
+		t.Fatalf("expected a 410 response with a custom body schema, got %+v", codes[410])
+	}
+}
@@ -1,11 +1,10 @@
 package oas
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/tjbrockmeyer/oasm"
 	"github.com/xeipuuv/gojsonschema"
-	"strconv"
+	"sort"
 	"strings"
 )
 
@@ -19,6 +18,11 @@ func (err malformedJSONError) Error() string {
 	return string(err)
 }
 
+// Malformed forms are reported in the same way as malformed JSON.
+func newMalformedFormError(err error) malformedJSONError {
+	return malformedJSONError(fmt.Sprint("request contains a malformed form: ", err.Error()))
+}
+
 func newJSONValidationError(result *gojsonschema.Result) jsonValidationError {
 	errorList := make([]string, 0, len(result.Errors()))
 	for _, e := range result.Errors() {
@@ -48,6 +52,28 @@ func (err jsonValidationError) Error() string {
 	return "JSONValidationError:\n\t" + strings.Join(err.Errors, "\n\t")
 }
 
-func errorToJSON(err error) json.RawMessage {
-	return []byte(fmt.Sprintf(`{"message":"Internal Server Error","details":%s}`, strconv.Quote(err.Error())))
+func newUnsupportedMediaTypeError(contentType string, supported oasm.MediaTypesMap) unsupportedMediaTypeError {
+	mimeTypes := make([]string, 0, len(supported))
+	for mimeType := range supported {
+		mimeTypes = append(mimeTypes, mimeType)
+	}
+	sort.Strings(mimeTypes)
+	return unsupportedMediaTypeError{
+		Type: "UnsupportedMediaTypeError",
+		Errors: []string{
+			fmt.Sprintf("content type (%s) is not one of: %s", contentType, strings.Join(mimeTypes, ", ")),
+		},
+	}
+}
+
+func newNotAcceptableError(accept string, available []string) *Problem {
+	return validationProblem(406, "NotAcceptableError", "The response cannot be sent as an accepted media type.", []string{
+		fmt.Sprintf("accept header (%s) does not allow any of: %s", accept, strings.Join(available, ", ")),
+	})
+}
+
+type unsupportedMediaTypeError jsonValidationError
+
+func (err unsupportedMediaTypeError) Error() string {
+	return "UnsupportedMediaTypeError:\n\t" + strings.Join(err.Errors, "\n\t")
 }
@@ -120,8 +120,12 @@ func defineEndpoints(spec oas.OpenAPI) {
 		Parameter("path", "item", "the item to put", true, strSchema, reflect.String).
 		RequestBody("Item details", true, oas.Ref("{Result}"), Result{}).
 		Response(201, "Created/Updated", nil).
+		ResponseHeader(201, "Location", "The location of the item", true, strSchema).
 		MustDefine(func(data oas.Data) (interface{}, error) {
-			return json.RawMessage(fmt.Sprintf(`"put item: '%s'"`, data.Params["item"])), nil
+			return oas.Response{
+				Status:  201,
+				Headers: map[string]string{"Location": fmt.Sprintf("/api/v1/item/%s", data.Params["item"])},
+			}, nil
 		})
 
 	spec.NewEndpoint("postAbc", "POST", "/series/{id}/abc/{path:.*}", "Create an ABC", "", []string{"Tag2"}).
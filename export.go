@@ -0,0 +1,315 @@
+package oas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	textTemplate "text/template"
+)
+
+// The tag of operations which have no tags, in exported references.
+const untaggedOperations = "default"
+
+var markdownReferenceTemplate = textTemplate.Must(textTemplate.New("reference.md").Funcs(textTemplate.FuncMap{
+	"cell": func(s string) string {
+		return strings.ReplaceAll(strings.ReplaceAll(s, "|", `\|`), "\n", "<br>")
+	},
+	"compact": func(s string) string {
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, []byte(s)); err != nil {
+			return s
+		}
+		return buf.String()
+	},
+}).ParseFS(docsTemplates, "docs-templates/reference.md"))
+
+// The contents of an exported reference of the API.
+type reference struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+	Tags        []referenceTag
+	Schemas     []referenceSchema
+}
+
+type referenceTag struct {
+	Name        string
+	Description string
+	Operations  []referenceOperation
+}
+
+type referenceOperation struct {
+	Method      string
+	Path        string
+	OperationId string
+	Summary     string
+	Description string
+	Deprecated  bool
+	Security    []string
+	Parameters  []referenceParameter
+	RequestBody *referenceRequestBody
+	Responses   []referenceResponse
+}
+
+type referenceParameter struct {
+	Name        string
+	In          string
+	Description string
+	Required    bool
+	Schema      referenceSchema
+}
+
+type referenceRequestBody struct {
+	Description string
+	Required    bool
+	Media       []referenceMedia
+}
+
+type referenceResponse struct {
+	Status      string
+	Description string
+	Headers     []referenceParameter
+	Media       []referenceMedia
+}
+
+type referenceMedia struct {
+	MimeType string
+	Schema   referenceSchema
+	Example  string
+}
+
+// A schema, which is either a reference to a named schema or is shown as JSON.
+type referenceSchema struct {
+	Name    string
+	Ref     string
+	JSON    string
+	Example string
+}
+
+func (o *openAPI) ExportHTML(w io.Writer) error {
+	ref, err := o.reference()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = docsUITemplates.ExecuteTemplate(&buf, "reference.html", ref); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func (o *openAPI) ExportMarkdown(w io.Writer) error {
+	ref, err := o.reference()
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err = markdownReferenceTemplate.Execute(&buf, ref); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+// Read the contents of an exported reference from the JSON encoding of the doc.
+func (o *openAPI) reference() (reference, error) {
+	b, err := o.docJSON()
+	if err != nil {
+		return reference{}, err
+	}
+	var doc map[string]interface{}
+	if err = json.Unmarshal(b, &doc); err != nil {
+		return reference{}, err
+	}
+
+	info := mapOf(doc["info"])
+	ref := reference{
+		Title:       stringOf(info["title"]),
+		Version:     stringOf(info["version"]),
+		Description: referenceText(info["description"]),
+	}
+	servers, _ := doc["servers"].([]interface{})
+	for _, server := range servers {
+		ref.Servers = append(ref.Servers, stringOf(mapOf(server)["url"]))
+	}
+
+	// Tags are listed in the order they are declared, followed by any undeclared tags.
+	tagIndexes := make(map[string]int)
+	addTag := func(name, description string) int {
+		if i, ok := tagIndexes[name]; ok {
+			return i
+		}
+		tagIndexes[name] = len(ref.Tags)
+		ref.Tags = append(ref.Tags, referenceTag{Name: name, Description: description})
+		return len(ref.Tags) - 1
+	}
+	tags, _ := doc["tags"].([]interface{})
+	for _, tag := range tags {
+		addTag(stringOf(mapOf(tag)["name"]), referenceText(mapOf(tag)["description"]))
+	}
+	paths := mapOf(doc["paths"])
+	for _, p := range sortedKeys(paths) {
+		pathItem := mapOf(paths[p])
+		for _, method := range operationMethods {
+			operation, ok := pathItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			op := referenceOperationOf(p, method, operation)
+			var names []string
+			_ = convertJSON(operation["tags"], &names)
+			if len(names) == 0 {
+				names = []string{untaggedOperations}
+			}
+			for _, name := range names {
+				i := addTag(name, "")
+				ref.Tags[i].Operations = append(ref.Tags[i].Operations, op)
+			}
+		}
+	}
+	withOperations := ref.Tags[:0]
+	for _, tag := range ref.Tags {
+		if len(tag.Operations) > 0 {
+			withOperations = append(withOperations, tag)
+		}
+	}
+	ref.Tags = withOperations
+
+	schemas := mapOf(mapOf(doc["components"])["schemas"])
+	for _, name := range sortedKeys(schemas) {
+		schema := referenceSchemaOf(schemas[name])
+		schema.Name = name
+		ref.Schemas = append(ref.Schemas, schema)
+	}
+	return ref, nil
+}
+
+func referenceOperationOf(path, method string, operation map[string]interface{}) referenceOperation {
+	op := referenceOperation{
+		Method:      strings.ToUpper(method),
+		Path:        path,
+		OperationId: stringOf(operation["operationId"]),
+		Summary:     stringOf(operation["summary"]),
+		Description: referenceText(operation["description"]),
+	}
+	op.Deprecated, _ = operation["deprecated"].(bool)
+	var security []map[string][]string
+	_ = convertJSON(operation["security"], &security)
+	for _, requirement := range security {
+		names := make([]string, 0, len(requirement))
+		for name, scopes := range requirement {
+			if len(scopes) > 0 {
+				name += " (" + strings.Join(scopes, ", ") + ")"
+			}
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		op.Security = append(op.Security, strings.Join(names, " and "))
+	}
+
+	params, _ := operation["parameters"].([]interface{})
+	for _, param := range params {
+		op.Parameters = append(op.Parameters, referenceParameterOf(mapOf(param)))
+	}
+	if requestBody, ok := operation["requestBody"].(map[string]interface{}); ok {
+		op.RequestBody = &referenceRequestBody{
+			Description: referenceText(requestBody["description"]),
+			Media:       referenceMediaOf(mapOf(requestBody["content"])),
+		}
+		op.RequestBody.Required, _ = requestBody["required"].(bool)
+	}
+
+	// Responses are listed by status, with ranges after their status codes and the default response last.
+	responses := mapOf(operation["responses"])
+	statuses := sortedKeys(responses)
+	sort.SliceStable(statuses, func(i, j int) bool {
+		return statuses[j] == "default" && statuses[i] != "default"
+	})
+	for _, status := range statuses {
+		response := mapOf(responses[status])
+		r := referenceResponse{
+			Status:      status,
+			Description: referenceText(response["description"]),
+			Media:       referenceMediaOf(mapOf(response["content"])),
+		}
+		headers := mapOf(response["headers"])
+		for _, name := range sortedKeys(headers) {
+			header := referenceParameterOf(mapOf(headers[name]))
+			header.Name = name
+			header.In = "header"
+			r.Headers = append(r.Headers, header)
+		}
+		op.Responses = append(op.Responses, r)
+	}
+	return op
+}
+
+func referenceParameterOf(param map[string]interface{}) referenceParameter {
+	p := referenceParameter{
+		Name:        stringOf(param["name"]),
+		In:          stringOf(param["in"]),
+		Description: referenceText(param["description"]),
+		Schema:      referenceSchemaOf(param["schema"]),
+	}
+	p.Required, _ = param["required"].(bool)
+	if example, ok := param["example"]; ok {
+		p.Schema.Example = referenceJSON(example)
+	}
+	return p
+}
+
+func referenceMediaOf(content map[string]interface{}) []referenceMedia {
+	media := make([]referenceMedia, 0, len(content))
+	for _, mimeType := range sortedKeys(content) {
+		m := mapOf(content[mimeType])
+		r := referenceMedia{
+			MimeType: mimeType,
+			Schema:   referenceSchemaOf(m["schema"]),
+		}
+		if example, ok := m["example"]; ok {
+			r.Example = referenceJSON(example)
+		} else if examples := mapOf(m["examples"]); len(examples) > 0 {
+			r.Example = referenceJSON(mapOf(examples[sortedKeys(examples)[0]])["value"])
+		} else {
+			r.Example = r.Schema.Example
+		}
+		media = append(media, r)
+	}
+	return media
+}
+
+func referenceSchemaOf(v interface{}) referenceSchema {
+	schema := mapOf(v)
+	if schema == nil {
+		return referenceSchema{}
+	}
+	if ref, ok := schema["$ref"].(string); ok && len(schema) == 1 && strings.HasPrefix(ref, componentSchemasRef) {
+		return referenceSchema{Ref: strings.TrimPrefix(ref, componentSchemasRef)}
+	}
+	s := referenceSchema{JSON: referenceJSON(schema)}
+	if example, ok := schema["example"]; ok {
+		s.Example = referenceJSON(example)
+	} else if examples, ok := schema["examples"].([]interface{}); ok && len(examples) > 0 {
+		s.Example = referenceJSON(examples[0])
+	}
+	return s
+}
+
+// Format a value of the doc as indented JSON.
+func referenceJSON(v interface{}) string {
+	b, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// Get the text of a description, undoing the line breaks which are added to operation descriptions.
+func referenceText(v interface{}) string {
+	return strings.ReplaceAll(stringOf(v), "<br/>", "\n")
+}
@@ -0,0 +1,141 @@
+package oas
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tjbrockmeyer/oasm"
+)
+
+// Create a spec with tagged, untagged and deprecated endpoints, and a named schema.
+func exportSpec(t *testing.T) OpenAPI {
+	t.Helper()
+	dir := t.TempDir()
+	item := `{"type": "object", "example": {"name": "a"}}`
+	if err := os.WriteFile(filepath.Join(dir, "Item.json"), []byte(item), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec, _, err := NewOpenAPI("Test", "The test API.", "http://localhost/api", "1.0.0", dir, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec.Doc().Tags = []oasm.Tag{{Name: "items", Description: "Items <b>in stock</b>."}, {Name: "unused"}}
+	spec.NewEndpoint("getItem", "GET", "/items/{id}", "Get an item.", "", []string{"items"}).
+		Parameter(oasm.InPath, "id", "The id of the item.", true, map[string]interface{}{"type": "integer"}, reflect.Int).
+		Parameter(oasm.InQuery, "filter", "Either a|b.", false, map[string]interface{}{"type": "string"}, reflect.String).
+		Response(200, "The item.", Ref("{Item}")).
+		Response(404, "No item.", nil).
+		MustDefine(func(Data) (interface{}, error) { return nil, nil })
+	spec.NewEndpoint("putItem", "PUT", "/items/{id}", "", "", []string{"items"}).
+		Parameter(oasm.InPath, "id", "", true, map[string]interface{}{"type": "integer"}, reflect.Int).
+		RequestBody("The new item.", true, map[string]interface{}{"type": "object"}, nil).
+		Response(204, "Saved.", nil).
+		Deprecate("Use patchItem instead.").
+		MustDefine(func(Data) (interface{}, error) { return nil, nil })
+	spec.NewEndpoint("health", "GET", "/health", "", "", nil).
+		Response(200, "Healthy.", nil).
+		MustDefine(func(Data) (interface{}, error) { return nil, nil })
+	return spec
+}
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		name     string
+		export   func(OpenAPI, *bytes.Buffer) error
+		contains []string
+		excludes []string
+	}{
+		{"html", func(spec OpenAPI, buf *bytes.Buffer) error { return spec.ExportHTML(buf) },
+			[]string{
+				"<title>Test 1.0.0</title>",
+				`<p>Servers: <code>http://localhost/api</code></p>`,
+				`<h2 id="tag-items">items</h2>`,
+				"Items &lt;b&gt;in stock&lt;/b&gt;.",
+				`<h2 id="tag-` + untaggedOperations + `">`,
+				`<span class="method">GET</span> <code>/items/{id}</code> Get an item.`,
+				"<td><code>id</code></td>",
+				"Either a|b.",
+				`<a href="#schema-Item">Item</a>`,
+				`<h3 class="deprecated">`,
+				"Request Body (required)",
+				"The new item.",
+				`<h3 id="schema-Item">Item</h3>`,
+			},
+			[]string{"<b>in stock</b>", `id="tag-unused"`}},
+		{"markdown", func(spec OpenAPI, buf *bytes.Buffer) error { return spec.ExportMarkdown(buf) },
+			[]string{
+				"# Test 1.0.0",
+				"Servers: `http://localhost/api`",
+				"## items",
+				"Items <b>in stock</b>.",
+				"## " + untaggedOperations,
+				"### `GET /items/{id}` Get an item.",
+				"| `id` | path | yes | `{\"type\":\"integer\"}` | The id of the item. |",
+				"| `filter` | query | no | `{\"type\":\"string\"}` | Either a\\|b. |",
+				"[Item](#schema-Item)",
+				"### ~~`PUT /items/{id}`~~",
+				"#### Request Body (required)",
+				"### Item",
+				"Example:",
+			},
+			[]string{"## unused"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := tt.export(exportSpec(t), &buf); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			for _, s := range tt.contains {
+				if !strings.Contains(out, s) {
+					t.Fatalf("expected the reference to contain %s:\n%s", s, out)
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(out, s) {
+					t.Fatalf("expected the reference not to contain %s:\n%s", s, out)
+				}
+			}
+		})
+	}
+}
+
+func TestReferenceOrder(t *testing.T) {
+	spec := exportSpec(t)
+	spec.NewEndpoint("listItems", "GET", "/items", "", "", []string{"items"}).
+		ResponseRange(2, "Success.", nil).
+		DefaultResponse("Failure.", nil).
+		Response(200, "The items.", nil).
+		MustDefine(func(Data) (interface{}, error) { return nil, nil })
+
+	ref, err := spec.(*openAPI).reference()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var tags []string
+	for _, tag := range ref.Tags {
+		tags = append(tags, tag.Name)
+	}
+	if expected := []string{"items", untaggedOperations}; !reflect.DeepEqual(tags, expected) {
+		t.Fatalf("expected the tags %v, got %v", expected, tags)
+	}
+	var operations []string
+	for _, op := range ref.Tags[0].Operations {
+		operations = append(operations, op.OperationId)
+	}
+	if expected := []string{"listItems", "getItem", "putItem"}; !reflect.DeepEqual(operations, expected) {
+		t.Fatalf("expected the operations %v, got %v", expected, operations)
+	}
+	var statuses []string
+	for _, r := range ref.Tags[0].Operations[0].Responses {
+		statuses = append(statuses, r.Status)
+	}
+	if expected := []string{"200", "2XX", "default"}; !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("expected the statuses %v, got %v", expected, statuses)
+	}
+}
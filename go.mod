@@ -1,12 +1,17 @@
 module github.com/tjbrockmeyer/oas
 
-go 1.14
+go 1.22
 
 require (
 	github.com/gorilla/mux v1.7.4
-	github.com/otiai10/copy v1.0.2
 	github.com/pkg/errors v0.9.1
 	github.com/tjbrockmeyer/oasm v1.0.0
 	github.com/tjbrockmeyer/vjsonschema v1.0.0
 	github.com/xeipuuv/gojsonschema v1.2.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
+	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
 )
@@ -0,0 +1,42 @@
+package oas
+
+import (
+	"io"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Create a spec without schemas, which routes requests itself.
+func newTestSpec(t *testing.T) OpenAPI {
+	t.Helper()
+	spec, _, err := NewOpenAPI("Test", "The test API.", "http://localhost/api", "1.0.0", t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return spec
+}
+
+// Send a request to the spec, returning the recorded response.
+func serve(spec OpenAPI, method, target string, body io.Reader, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, body)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	spec.ServeHTTP(w, r)
+	return w
+}
+
+func assertStatus(t *testing.T, w *httptest.ResponseRecorder, status int) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("expected status %v, got %v: %s", status, w.Code, strings.TrimSpace(w.Body.String()))
+	}
+}
+
+// Get the settable field of the struct which the pointer points to.
+func reflectField(ptr interface{}, name string) reflect.Value {
+	return reflect.ValueOf(ptr).Elem().FieldByName(name)
+}
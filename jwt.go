@@ -0,0 +1,343 @@
+package oas
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// The default claim which holds the scopes of a JWT.
+	DefaultScopeClaim = "scope"
+	// The default minimum time between reloads of the JSON Web Key Set file.
+	DefaultJWKSReloadInterval = time.Minute
+)
+
+// The settings for verifying JWTs. See: NewJWTVerifier
+type JWTConfig struct {
+	// Keys for verifying tokens, mapped by key id. The key with an empty id is used for tokens without a key id.
+	// Keys must be []byte for HS256, *rsa.PublicKey for RS256, or *ecdsa.PublicKey for ES256.
+	Keys map[string]interface{}
+	// A JSON Web Key Set file, the keys of which are added to Keys.
+	// The file is reloaded when a token has a key id which is not known, so that keys can be rotated
+	// by replacing the file, and the keys which were removed from it are no longer used.
+	JWKSFile string
+	// The minimum time between reloads of the JWKS file. (Default: DefaultJWKSReloadInterval)
+	JWKSReloadInterval time.Duration
+	// The required issuer (iss claim), if not empty.
+	Issuer string
+	// The required audience (aud claim), if not empty.
+	Audience string
+	// The claim which holds the scopes of the token, as a space separated string or an array of strings.
+	// (Default: DefaultScopeClaim)
+	ScopeClaim string
+	// The clock skew allowed when checking the exp and nbf claims.
+	Leeway time.Duration
+	// Whether tokens without an exp claim are rejected. Otherwise, such tokens never expire.
+	RequireExp bool
+}
+
+// The claims of a verified JWT, which are set as the principal of the request.
+type JWTClaims map[string]interface{}
+
+// Get the scopes of the claims from the scope claim.
+func (c JWTClaims) Scopes(scopeClaim string) []string {
+	return c.stringList(scopeClaim)
+}
+
+// Get a claim which is either a space separated string or an array of strings as a list.
+func (c JWTClaims) stringList(claim string) []string {
+	switch values := c[claim].(type) {
+	case string:
+		return strings.Fields(values)
+	case []interface{}:
+		list := make([]string, 0, len(values))
+		for _, s := range values {
+			if s, ok := s.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+type jwtVerifier struct {
+	config JWTConfig
+	keys   map[string]interface{}
+
+	// The keys of the JWKS file, which are replaced when it is reloaded.
+	jwksLock     sync.RWMutex
+	jwksKeys     map[string]interface{}
+	jwksLoadedAt time.Time
+}
+
+// Create a security handler which verifies JWT bearer tokens (for http bearer, oauth2, and openIdConnect schemes).
+// Tokens must be signed using HS256, RS256, or ES256 by one of the configured keys, and must be valid according to
+// their exp and nbf claims, as well as the issuer and audience if they are configured.
+// Tokens without all of the scopes of the security requirement are rejected with a 403 problem
+// which lists the required scopes.
+// The principal of the request is the JWTClaims of the token.
+func NewJWTVerifier(config JWTConfig) (SecurityHandler, error) {
+	v := &jwtVerifier{
+		config: config,
+		keys:   make(map[string]interface{}, len(config.Keys)),
+	}
+	if v.config.ScopeClaim == "" {
+		v.config.ScopeClaim = DefaultScopeClaim
+	}
+	if v.config.JWKSReloadInterval <= 0 {
+		v.config.JWKSReloadInterval = DefaultJWKSReloadInterval
+	}
+	for kid, key := range config.Keys {
+		switch key.(type) {
+		case []byte, *rsa.PublicKey, *ecdsa.PublicKey:
+			v.keys[kid] = key
+		default:
+			return nil, fmt.Errorf("unsupported key type for key id '%s': %T", kid, key)
+		}
+	}
+	if config.JWKSFile != "" {
+		if err := v.loadJWKS(); err != nil {
+			return nil, err
+		}
+	}
+	if len(v.keys) == 0 && len(v.jwksKeys) == 0 {
+		return nil, errors.New("no keys were configured for verifying JWTs")
+	}
+	return v.handle, nil
+}
+
+func (v *jwtVerifier) handle(_ Data, credentials Credentials, scopes []string) (interface{}, error) {
+	claims, err := v.verify(credentials.Value)
+	if err != nil {
+		return nil, err
+	}
+	granted := claims.Scopes(v.config.ScopeClaim)
+	missing := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !containsString(granted, scope) {
+			missing = append(missing, scope)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, NewProblem(403, "The token is missing required scopes: "+strings.Join(missing, ", ")).
+			With("requiredScopes", scopes).
+			With("missingScopes", missing)
+	}
+	return claims, nil
+}
+
+// Verify the signature and the claims of the token.
+// Tokens without an exp claim are accepted unless RequireExp is set.
+func (v *jwtVerifier) verify(token string) (JWTClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("token is not a JWT")
+	}
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return nil, errors.WithMessage(err, "malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.WithMessage(err, "malformed token signature")
+	}
+	key, ok := v.key(header.Kid)
+	if !ok {
+		return nil, fmt.Errorf("unknown key id '%s'", header.Kid)
+	}
+	if err = verifyJWTSignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims JWTClaims
+	if err = decodeJWTPart(parts[1], &claims); err != nil {
+		return nil, errors.WithMessage(err, "malformed token claims")
+	}
+	now := time.Now()
+	exp, ok := claims["exp"].(float64)
+	if !ok && v.config.RequireExp {
+		return nil, errors.New("token has no expiration time")
+	}
+	if ok && now.After(jwtTime(exp).Add(v.config.Leeway)) {
+		return nil, errors.New("token is expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Before(jwtTime(nbf).Add(-v.config.Leeway)) {
+		return nil, errors.New("token is not valid yet")
+	}
+	if v.config.Issuer != "" && claims["iss"] != v.config.Issuer {
+		return nil, errors.New("token has the wrong issuer")
+	}
+	if v.config.Audience != "" && !containsString(claims.stringList("aud"), v.config.Audience) {
+		return nil, errors.New("token has the wrong audience")
+	}
+	return claims, nil
+}
+
+// Verify the signature of the signed content, making sure that the algorithm matches the type of the key.
+func verifyJWTSignature(alg string, key interface{}, signed, signature []byte) error {
+	hash := sha256.Sum256(signed)
+	switch key := key.(type) {
+	case []byte:
+		if alg == "HS256" {
+			mac := hmac.New(sha256.New, key)
+			mac.Write(signed)
+			if !hmac.Equal(mac.Sum(nil), signature) {
+				return errors.New("invalid token signature")
+			}
+			return nil
+		}
+	case *rsa.PublicKey:
+		if alg == "RS256" {
+			if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature); err != nil {
+				return errors.New("invalid token signature")
+			}
+			return nil
+		}
+	case *ecdsa.PublicKey:
+		if alg == "ES256" {
+			if len(signature) != 64 {
+				return errors.New("invalid token signature")
+			}
+			r := new(big.Int).SetBytes(signature[:32])
+			s := new(big.Int).SetBytes(signature[32:])
+			if !ecdsa.Verify(key, hash[:], r, s) {
+				return errors.New("invalid token signature")
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("token algorithm %s cannot be used with the key", alg)
+}
+
+// Find the key with the id, reloading the JWKS file if the key is not known.
+func (v *jwtVerifier) key(kid string) (interface{}, bool) {
+	if key, ok := v.keys[kid]; ok {
+		return key, true
+	}
+	if v.config.JWKSFile == "" {
+		return nil, false
+	}
+	v.jwksLock.RLock()
+	key, ok := v.jwksKeys[kid]
+	reload := !ok && time.Since(v.jwksLoadedAt) >= v.config.JWKSReloadInterval
+	v.jwksLock.RUnlock()
+	if !reload {
+		return key, ok
+	}
+	// If the file cannot be loaded, the keys which were loaded before are kept.
+	_ = v.loadJWKS()
+	v.jwksLock.RLock()
+	defer v.jwksLock.RUnlock()
+	key, ok = v.jwksKeys[kid]
+	return key, ok
+}
+
+// Load the keys of the JSON Web Key Set file, replacing those which were loaded before.
+func (v *jwtVerifier) loadJWKS() error {
+	v.jwksLock.Lock()
+	defer v.jwksLock.Unlock()
+	if !v.jwksLoadedAt.IsZero() && time.Since(v.jwksLoadedAt) < v.config.JWKSReloadInterval {
+		return nil
+	}
+	v.jwksLoadedAt = time.Now()
+	keys, err := readJWKS(v.config.JWKSFile)
+	if err != nil {
+		return errors.WithMessage(err, "failed to load the JWKS file")
+	}
+	v.jwksKeys = keys
+	return nil
+}
+
+// Read the keys of a JSON Web Key Set file, mapped by key id.
+// Keys which cannot be used for verifying tokens, such as those of other key types or curves, are skipped,
+// so that a key set which is shared with other services can be used.
+func readJWKS(file string) (map[string]interface{}, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var jwks struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			Use string `json:"use"`
+			Crv string `json:"crv"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+			X   string `json:"x"`
+			Y   string `json:"y"`
+			K   string `json:"k"`
+		} `json:"keys"`
+	}
+	if err = json.Unmarshal(b, &jwks); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]interface{}, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, err1 := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, err2 := base64.RawURLEncoding.DecodeString(jwk.E)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("malformed RSA key '%s'", jwk.Kid)
+			}
+			// The exponent must fit in an int, and crypto/rsa only accepts exponents from 2 to 2^31-1.
+			exponent := new(big.Int).SetBytes(e)
+			if !exponent.IsInt64() || exponent.Int64() < 2 || exponent.Int64() > math.MaxInt32 {
+				return nil, fmt.Errorf("malformed RSA key '%s': the exponent is out of range", jwk.Kid)
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}
+		case "EC":
+			if jwk.Crv != "P-256" {
+				continue
+			}
+			x, err1 := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, err2 := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("malformed EC key '%s'", jwk.Kid)
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		case "oct":
+			k, err := base64.RawURLEncoding.DecodeString(jwk.K)
+			if err != nil {
+				return nil, fmt.Errorf("malformed symmetric key '%s'", jwk.Kid)
+			}
+			keys[jwk.Kid] = k
+		}
+	}
+	return keys, nil
+}
+
+func decodeJWTPart(part string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+func jwtTime(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second)))
+}
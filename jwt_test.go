@@ -0,0 +1,298 @@
+package oas
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Create a JWT with the header and claims, signed by the key using the algorithm of the header.
+// The signature of ES256 tokens is the concatenation of r and s, unless der is set, and it is empty if the key is nil.
+func signJWT(t *testing.T, header, claims map[string]interface{}, key interface{}, der bool) string {
+	t.Helper()
+	encode := func(v interface{}) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	signed := encode(header) + "." + encode(claims)
+	hash := sha256.Sum256([]byte(signed))
+	var signature []byte
+	var err error
+	switch key := key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(signed))
+		signature = mac.Sum(nil)
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hash[:])
+	case *ecdsa.PrivateKey:
+		if der {
+			signature, err = ecdsa.SignASN1(rand.Reader, key, hash[:])
+			break
+		}
+		r, s, signErr := ecdsa.Sign(rand.Reader, key, hash[:])
+		signature, err = make([]byte, 64), signErr
+		r.FillBytes(signature[:32])
+		s.FillBytes(signature[32:])
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestJWTVerifier(t *testing.T) {
+	secret := []byte("secret")
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler, err := NewJWTVerifier(JWTConfig{
+		Keys:     map[string]interface{}{"hs": secret, "rs": &rsaKey.PublicKey, "es": &ecKey.PublicKey},
+		Issuer:   "issuer",
+		Audience: "api",
+		Leeway:   time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().Unix()
+	valid := func(changes map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{"iss": "issuer", "aud": "api", "exp": now + 3600, "scope": "read write"}
+		for k, v := range changes {
+			if v == nil {
+				delete(claims, k)
+			} else {
+				claims[k] = v
+			}
+		}
+		return claims
+	}
+	header := func(alg, kid string) map[string]interface{} {
+		return map[string]interface{}{"alg": alg, "typ": "JWT", "kid": kid}
+	}
+	rsaPublicKey, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublicKey})
+	hsToken := signJWT(t, header("HS256", "hs"), valid(nil), secret, false)
+	parts := strings.Split(hsToken, ".")
+	adminClaims := strings.Split(signJWT(t, header("HS256", "hs"), valid(map[string]interface{}{"scope": "admin"}), secret, false), ".")[1]
+
+	tests := []struct {
+		name    string
+		token   string
+		scopes  []string
+		wantErr string
+	}{
+		{"HS256", hsToken, nil, ""},
+		{"RS256", signJWT(t, header("RS256", "rs"), valid(nil), rsaKey, false), nil, ""},
+		{"ES256", signJWT(t, header("ES256", "es"), valid(nil), ecKey, false), nil, ""},
+		{"ES256 with a DER signature", signJWT(t, header("ES256", "es"), valid(nil), ecKey, true), nil, "invalid token signature"},
+		{"HS256 signed with the public key of an RSA key", signJWT(t, header("HS256", "rs"), valid(nil), rsaPublicKeyPEM, false), nil, "cannot be used with the key"},
+		{"RS256 with an HMAC key", signJWT(t, header("RS256", "hs"), valid(nil), rsaKey, false), nil, "cannot be used with the key"},
+		{"ES256 with an RSA key", signJWT(t, header("ES256", "rs"), valid(nil), ecKey, false), nil, "cannot be used with the key"},
+		{"alg none", signJWT(t, header("none", "hs"), valid(nil), nil, false), nil, "cannot be used with the key"},
+		{"tampered claims", parts[0] + "." + adminClaims + "." + parts[2], nil, "invalid token signature"},
+		{"unknown key id", signJWT(t, header("HS256", "other"), valid(nil), secret, false), nil, "unknown key id"},
+		{"not a JWT", "token", nil, "not a JWT"},
+		{"expired", signJWT(t, header("HS256", "hs"), valid(map[string]interface{}{"exp": now - 120}), secret, false), nil, "expired"},
+		{"expired within the leeway", signJWT(t, header("HS256", "hs"), valid(map[string]interface{}{"exp": now - 30}), secret, false), nil, ""},
+		{"not valid yet", signJWT(t, header("HS256", "hs"), valid(map[string]interface{}{"nbf": now + 120}), secret, false), nil, "not valid yet"},
+		{"not valid yet within the leeway", signJWT(t, header("HS256", "hs"), valid(map[string]interface{}{"nbf": now + 30}), secret, false), nil, ""},
+		{"wrong issuer", signJWT(t, header("HS256", "hs"), valid(map[string]interface{}{"iss": "other"}), secret, false), nil, "wrong issuer"},
+		{"missing issuer", signJWT(t, header("HS256", "hs"), valid(map[string]interface{}{"iss": nil}), secret, false), nil, "wrong issuer"},
+		{"wrong audience", signJWT(t, header("HS256", "hs"), valid(map[string]interface{}{"aud": "other"}), secret, false), nil, "wrong audience"},
+		{"audience list", signJWT(t, header("HS256", "hs"), valid(map[string]interface{}{"aud": []string{"other", "api"}}), secret, false), nil, ""},
+		{"granted scopes", hsToken, []string{"read", "write"}, ""},
+		{"missing scopes", hsToken, []string{"read", "admin"}, "missing required scopes: admin"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, err := handler(Data{}, Credentials{Value: tt.token}, tt.scopes)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected the token to be valid, got %v", err)
+				}
+				if _, ok := principal.(JWTClaims); !ok {
+					t.Fatalf("expected the principal to be the claims, got %T", principal)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierJWKSRotation(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "jwks.json")
+	keys := make(map[string]*ecdsa.PrivateKey)
+	writeJWKS := func(kids ...string) {
+		t.Helper()
+		jwks := make([]map[string]string, 0, len(kids))
+		for _, kid := range kids {
+			if keys[kid] == nil {
+				key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+				if err != nil {
+					t.Fatal(err)
+				}
+				keys[kid] = key
+			}
+			x, y := make([]byte, 32), make([]byte, 32)
+			keys[kid].X.FillBytes(x)
+			keys[kid].Y.FillBytes(y)
+			jwks = append(jwks, map[string]string{
+				"kty": "EC", "kid": kid, "use": "sig", "crv": "P-256",
+				"x": base64.RawURLEncoding.EncodeToString(x), "y": base64.RawURLEncoding.EncodeToString(y),
+			})
+		}
+		b, err := json.Marshal(map[string]interface{}{"keys": jwks})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(file, b, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	token := func(kid string) string {
+		header := map[string]interface{}{"alg": "ES256", "kid": kid}
+		return signJWT(t, header, map[string]interface{}{"sub": "user"}, keys[kid], false)
+	}
+
+	writeJWKS("first")
+	reloading, err := NewJWTVerifier(JWTConfig{JWKSFile: file, JWKSReloadInterval: time.Nanosecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	throttled, err := NewJWTVerifier(JWTConfig{JWKSFile: file})
+	if err != nil {
+		t.Fatal(err)
+	}
+	firstToken := token("first")
+	writeJWKS("second")
+	secondToken := token("second")
+
+	tests := []struct {
+		name    string
+		handler SecurityHandler
+		token   string
+		valid   bool
+	}{
+		{"a new key is loaded", reloading, secondToken, true},
+		{"a removed key is not used", reloading, firstToken, false},
+		{"keys loaded recently are kept", throttled, firstToken, true},
+		{"the file is not reloaded within the interval", throttled, secondToken, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.handler(Data{}, Credentials{Value: tt.token}, nil)
+			if (err == nil) != tt.valid {
+				t.Fatalf("expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestJWTVerifierRequireExp(t *testing.T) {
+	secret := []byte("secret")
+	header := map[string]interface{}{"alg": "HS256"}
+	withExp := signJWT(t, header, map[string]interface{}{"exp": time.Now().Unix() + 60}, secret, false)
+	withoutExp := signJWT(t, header, map[string]interface{}{"sub": "user"}, secret, false)
+	tests := []struct {
+		name       string
+		requireExp bool
+		token      string
+		valid      bool
+	}{
+		{"exp is optional", false, withoutExp, true},
+		{"exp is required", true, withoutExp, false},
+		{"exp is required and set", true, withExp, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, err := NewJWTVerifier(JWTConfig{Keys: map[string]interface{}{"": secret}, RequireExp: tt.requireExp})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = handler(Data{}, Credentials{Value: tt.token}, nil)
+			if (err == nil) != tt.valid {
+				t.Fatalf("expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestReadJWKS(t *testing.T) {
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	n := encode([]byte{0xc3, 0x5a, 0x01})
+	tests := []struct {
+		name    string
+		key     map[string]string
+		kids    []string
+		wantErr string
+	}{
+		{"RSA", map[string]string{"kty": "RSA", "kid": "rs", "n": n, "e": "AQAB"}, []string{"rs"}, ""},
+		{"symmetric", map[string]string{"kty": "oct", "kid": "hs", "k": encode([]byte("secret"))}, []string{"hs"}, ""},
+		{"encryption key", map[string]string{"kty": "oct", "kid": "enc", "use": "enc", "k": "AA"}, nil, ""},
+		{"OKP key", map[string]string{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": "AA"}, nil, ""},
+		{"P-384 key", map[string]string{"kty": "EC", "kid": "es", "crv": "P-384", "x": "AA", "y": "AA"}, nil, ""},
+		{"malformed RSA key", map[string]string{"kty": "RSA", "kid": "rs", "n": "!", "e": "AQAB"}, nil, "malformed RSA key 'rs'"},
+		{"exponent above the int range", map[string]string{"kty": "RSA", "kid": "rs", "n": n, "e": encode([]byte{1, 0, 0, 0, 0, 0, 0, 0, 1})}, nil, "out of range"},
+		{"exponent above 31 bits", map[string]string{"kty": "RSA", "kid": "rs", "n": n, "e": encode([]byte{0x80, 0, 0, 0})}, nil, "out of range"},
+		{"exponent of one", map[string]string{"kty": "RSA", "kid": "rs", "n": n, "e": "AQ"}, nil, "out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "jwks.json")
+			b, err := json.Marshal(map[string]interface{}{"keys": []map[string]string{tt.key}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = os.WriteFile(file, b, 0600); err != nil {
+				t.Fatal(err)
+			}
+			keys, err := readJWKS(file)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != len(tt.kids) {
+				t.Fatalf("expected the keys %v, got %v", tt.kids, keys)
+			}
+			for _, kid := range tt.kids {
+				if _, ok := keys[kid]; !ok {
+					t.Fatalf("expected the key %s, got %v", kid, keys)
+				}
+			}
+		})
+	}
+}
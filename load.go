@@ -0,0 +1,574 @@
+package oas
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/oasm"
+	"gopkg.in/yaml.v3"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The prefix of references to the schemas in the components of a spec.
+const componentSchemasRef = "#/components/schemas/"
+
+// The methods of a path item which are operations, in the order they are defined.
+var operationMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// Load an existing OpenAPI Specification from a JSON or YAML file, and define its operations using the handlers,
+// which are mapped by operationId.
+//
+// References to other files (such as "schemas.yaml#/Item") are resolved relative to the file which contains them.
+// The components.schemas of the spec are used as the JSON Schemas of the API, and the parameters, request bodies,
+// responses, and security requirements of each operation are declared on its endpoint.
+// Parameters must be described by a schema, since those described by content are not supported.
+// Every operation must have an operationId and a handler, and every handler must belong to an operation.
+// The url of the first server, with its variables replaced by their default values, is where the endpoints are served.
+//
+// Parameters:
+//   file         - The path to the OpenAPI Specification (.json, .yaml, or .yml)
+//   handlers     - The handlers for the operations of the spec, mapped by operationId
+//   security     - The security handlers of the spec, mapped by security scheme name, which may be nil
+//                  to only document the security requirements. See: OpenAPI.SetSecurityHandler
+//   routeCreator - A function which can add middleware and mount an endpoint at an http path, which may be nil
+//                  if the spec itself is used as the http.Handler of the API
+//
+// Returns:
+//   spec       - The specification object
+//   fileServer - The fileServer http.Handler that can be mounted to show a Swagger UI for the API
+//   err        - Any error that may have occurred
+func LoadOpenAPI(
+	file string, handlers map[string]HandlerFunc, security map[string]SecurityHandler, routeCreator RouteCreator,
+) (spec OpenAPI, fileServer http.Handler, err error) {
+	l := &specLoader{files: make(map[string]interface{})}
+	raw, err := l.load(file)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to load the spec")
+	}
+	doc, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, nil, errors.New("the spec is not an object: " + file)
+	}
+	if err = checkOperationHandlers(doc, handlers); err != nil {
+		return nil, nil, err
+	}
+
+	info := mapOf(doc["info"])
+	serverUrl := "/"
+	if servers, ok := doc["servers"].([]interface{}); ok && len(servers) > 0 {
+		if serverUrl, err = defaultServerUrl(mapOf(servers[0])); err != nil {
+			return nil, nil, err
+		}
+	}
+	var tags []oasm.Tag
+	if err = convertJSON(doc["tags"], &tags); err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to read the tags of the spec")
+	}
+	o, err := newOpenAPI(stringOf(info["title"]), stringOf(info["description"]), serverUrl, stringOf(info["version"]), tags, routeCreator)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = convertJSON(info, o.doc.Info); err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to read the info of the spec")
+	}
+	if servers, ok := doc["servers"]; ok {
+		if err = convertJSON(servers, &o.doc.Servers); err != nil {
+			return nil, nil, errors.WithMessage(err, "failed to read the servers of the spec")
+		}
+	}
+	if version := stringOf(doc["openapi"]); strings.HasPrefix(version, "3.") {
+		o.doc.OpenApi = version
+	}
+	if err = convertJSON(doc["webhooks"], &o.webhooks); err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to read the webhooks of the spec")
+	}
+	if err = convertJSON(doc["security"], &o.doc.Security); err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to read the security requirements of the spec")
+	}
+	components := mapOf(doc["components"])
+	if err = convertJSON(components["securitySchemes"], &o.doc.Components.SecuritySchemes); err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to read the security schemes of the spec")
+	}
+	for name, handler := range security {
+		o.SetSecurityHandler(name, handler)
+	}
+
+	// The schemas are validated as JSON Schemas, but documented as they were written.
+	schemas := mapOf(components["schemas"])
+	for name, schema := range schemas {
+		b, err := json.Marshal(toJSONSchema(schema))
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "failed to marshal schema: "+name)
+		}
+		if err = o.validatorBuilder.AddSchema(name, b); err != nil {
+			return nil, nil, errors.WithMessage(err, "failed to add schema: "+name)
+		}
+	}
+	if err = o.addSchemas(); err != nil {
+		return nil, nil, err
+	}
+	for name, schema := range schemas {
+		o.doc.Components.Schemas[name] = schema
+	}
+
+	paths := mapOf(doc["paths"])
+	for _, p := range sortedKeys(paths) {
+		resolved, err := resolveLocalRef(doc, paths[p])
+		if err != nil {
+			return nil, nil, errors.WithMessage(err, "failed to read path "+p)
+		}
+		pathItem := mapOf(resolved)
+		for _, method := range operationMethods {
+			operation, ok := pathItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if err = o.loadOperation(doc, p, method, pathItem, operation, handlers); err != nil {
+				return nil, nil, errors.WithMessage(err, fmt.Sprint("failed to define operation ", strings.ToUpper(method), " ", p))
+			}
+		}
+	}
+
+	if fileServer, err = o.newFileServer(); err != nil {
+		return nil, nil, err
+	}
+	return o, fileServer, nil
+}
+
+// Check that every operation of the spec has a handler, and that every handler belongs to an operation.
+func checkOperationHandlers(doc map[string]interface{}, handlers map[string]HandlerFunc) error {
+	operationIds := make(map[string]bool)
+	var missing, unknown []string
+	paths := mapOf(doc["paths"])
+	for _, p := range sortedKeys(paths) {
+		resolved, err := resolveLocalRef(doc, paths[p])
+		if err != nil {
+			return errors.WithMessage(err, "failed to read path "+p)
+		}
+		pathItem := mapOf(resolved)
+		for _, method := range operationMethods {
+			operation, ok := pathItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			operationId, _ := operation["operationId"].(string)
+			if operationId == "" {
+				return errors.New(fmt.Sprint("operation has no operationId: ", strings.ToUpper(method), " ", p))
+			}
+			operationIds[operationId] = true
+			if handlers[operationId] == nil {
+				missing = append(missing, operationId)
+			}
+		}
+	}
+	for operationId := range handlers {
+		if !operationIds[operationId] {
+			unknown = append(unknown, operationId)
+		}
+	}
+	sort.Strings(unknown)
+	switch {
+	case len(missing) > 0 && len(unknown) > 0:
+		return errors.New("operations have no handler: " + strings.Join(missing, ", ") +
+			"; handlers have no operation: " + strings.Join(unknown, ", "))
+	case len(missing) > 0:
+		return errors.New("operations have no handler: " + strings.Join(missing, ", "))
+	case len(unknown) > 0:
+		return errors.New("handlers have no operation: " + strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Get the url of a server of the spec, with each of its variables replaced by their default values.
+func defaultServerUrl(server map[string]interface{}) (string, error) {
+	serverUrl, ok := server["url"].(string)
+	if !ok {
+		return "/", nil
+	}
+	variables := mapOf(server["variables"])
+	for _, name := range sortedKeys(variables) {
+		value, ok := mapOf(variables[name])["default"].(string)
+		if !ok {
+			return "", errors.New("server variable has no default value: " + name)
+		}
+		serverUrl = strings.ReplaceAll(serverUrl, "{"+name+"}", value)
+	}
+	if strings.ContainsRune(serverUrl, '{') {
+		return "", errors.New("server url has a variable which is not defined: " + serverUrl)
+	}
+	return serverUrl, nil
+}
+
+// Declare and define the endpoint for an operation of the spec.
+func (o *openAPI) loadOperation(
+	doc map[string]interface{}, path, method string, pathItem, operation map[string]interface{},
+	handlers map[string]HandlerFunc,
+) error {
+	operationId := operation["operationId"].(string)
+	var tags []string
+	if err := convertJSON(operation["tags"], &tags); err != nil {
+		return errors.WithMessage(err, "failed to read the tags")
+	}
+	d := o.NewEndpoint(operationId, method, path, stringOf(operation["summary"]), stringOf(operation["description"]), tags)
+
+	// Parameters of the operation replace those of the path item with the same name and location.
+	var params []map[string]interface{}
+	for _, list := range []interface{}{pathItem["parameters"], operation["parameters"]} {
+		items, _ := list.([]interface{})
+		for _, item := range items {
+			resolved, err := resolveLocalRef(doc, item)
+			if err != nil {
+				return errors.WithMessage(err, "failed to read a parameter")
+			}
+			param := mapOf(resolved)
+			replaced := false
+			for i, p := range params {
+				if p["name"] == param["name"] && p["in"] == param["in"] {
+					params[i] = param
+					replaced = true
+				}
+			}
+			if !replaced {
+				params = append(params, param)
+			}
+		}
+	}
+	for _, param := range params {
+		in, name := stringOf(param["in"]), stringOf(param["name"])
+		if _, ok := param["content"]; ok {
+			return errors.New("parameters with content instead of a schema are not supported: " + in + " " + name)
+		}
+		required, _ := param["required"].(bool)
+		schema := toJSONSchema(param["schema"])
+		if schema == nil {
+			schema = map[string]interface{}{"type": "string"}
+		}
+		d = d.Parameter(in, name, stringOf(param["description"]), required, schema, o.parameterKind(schema))
+		if style, ok := param["style"].(string); ok {
+			explode, ok := param["explode"].(bool)
+			if !ok {
+				explode = style == StyleForm
+			}
+			d = d.ParameterStyle(in, name, style, explode)
+		} else if explode, ok := param["explode"].(bool); ok {
+			d = d.ParameterStyle(in, name, parameterStyles[in][0], explode)
+		}
+	}
+
+	if requestBody, ok := operation["requestBody"]; ok {
+		resolved, err := resolveLocalRef(doc, requestBody)
+		if err != nil {
+			return errors.WithMessage(err, "failed to read the request body")
+		}
+		requestBody := mapOf(resolved)
+		required, _ := requestBody["required"].(bool)
+		content := mapOf(requestBody["content"])
+		for _, mimeType := range sortedKeys(content) {
+			schema := toJSONSchema(mapOf(content[mimeType])["schema"])
+			if schema == nil {
+				schema = map[string]interface{}{}
+			}
+			d = d.RequestBodyContent(mimeType, stringOf(requestBody["description"]), required, schema, nil)
+		}
+	}
+
+	responses := mapOf(operation["responses"])
+	for _, key := range sortedKeys(responses) {
+		resolved, err := resolveLocalRef(doc, responses[key])
+		if err != nil {
+			return errors.WithMessage(err, "failed to read the response "+key)
+		}
+		response := mapOf(resolved)
+		description := stringOf(response["description"])
+		content := mapOf(response["content"])
+		mimeTypes := sortedKeys(content)
+		var schema interface{}
+		if len(mimeTypes) > 0 {
+			media := content[oasm.MimeJson]
+			if media == nil {
+				media = content[mimeTypes[0]]
+			}
+			if schema = toJSONSchema(mapOf(media)["schema"]); schema == nil {
+				schema = map[string]interface{}{}
+			}
+		}
+		code, err := strconv.Atoi(key)
+		switch {
+		case key == "default":
+			d = d.DefaultResponse(description, schema)
+		case len(key) == 3 && strings.HasSuffix(strings.ToUpper(key), "XX"):
+			class, _ := strconv.Atoi(key[:1])
+			d = d.ResponseRange(class, description, schema)
+		case err != nil:
+			return errors.New("invalid response status code: " + key)
+		default:
+			d = d.Response(code, description, schema)
+			if len(mimeTypes) > 0 && (len(mimeTypes) > 1 || mimeTypes[0] != oasm.MimeJson) {
+				d = d.ResponseMediaTypes(code, mimeTypes...)
+			}
+			headers := mapOf(response["headers"])
+			for _, name := range sortedKeys(headers) {
+				resolved, err := resolveLocalRef(doc, headers[name])
+				if err != nil {
+					return errors.WithMessage(err, "failed to read the response header "+name)
+				}
+				header := mapOf(resolved)
+				required, _ := header["required"].(bool)
+				d = d.ResponseHeader(code, name, stringOf(header["description"]), required, toJSONSchema(header["schema"]))
+			}
+		}
+	}
+
+	var security []oasm.SecurityRequirement
+	if err := convertJSON(operation["security"], &security); err != nil {
+		return errors.WithMessage(err, "failed to read the security requirements")
+	}
+	for _, requirement := range security {
+		d = d.Security(requirement)
+	}
+	if _, ok := operation["security"]; ok && len(security) == 0 {
+		d = d.NoSecurity()
+	}
+	if deprecated, _ := operation["deprecated"].(bool); deprecated {
+		d = d.Deprecate("")
+	}
+	_, err := d.Define(handlers[operationId])
+	return err
+}
+
+// Get the kind of a parameter from its schema.
+func (o *openAPI) parameterKind(schema interface{}) reflect.Kind {
+	s := o.resolveSchema(schema)
+	switch schemaType(s) {
+	case "array":
+		return reflect.Slice
+	case "object":
+		return reflect.Map
+	}
+	return schemaKind(s)
+}
+
+// Reads the files of a spec, resolving references between them.
+type specLoader struct {
+	// The contents of the files that have been read, mapped by their absolute path.
+	files map[string]interface{}
+}
+
+// Load a spec file, replacing references to other files with their contents.
+func (l *specLoader) load(file string) (interface{}, error) {
+	file, err := filepath.Abs(file)
+	if err != nil {
+		return nil, err
+	}
+	v, err := l.read(file)
+	if err != nil {
+		return nil, err
+	}
+	return l.resolveFileRefs(v, file, nil)
+}
+
+// Read and parse a JSON or YAML file.
+func (l *specLoader) read(file string) (interface{}, error) {
+	if v, ok := l.files[file]; ok {
+		return v, nil
+	}
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err = yaml.Unmarshal(b, &v); err != nil {
+		return nil, errors.WithMessage(err, "failed to parse "+file)
+	}
+	v = normalizeYAML(v)
+	l.files[file] = v
+	return v, nil
+}
+
+// Replace references to other files within a value of a file with the values they refer to.
+// Local references within other files are resolved as well, since they do not refer to the root spec.
+func (l *specLoader) resolveFileRefs(v interface{}, file string, stack []string) (interface{}, error) {
+	switch v := v.(type) {
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			var err error
+			if resolved[i], err = l.resolveFileRefs(item, file, stack); err != nil {
+				return nil, err
+			}
+		}
+		return resolved, nil
+	case map[string]interface{}:
+		if ref, ok := v["$ref"].(string); ok && (!strings.HasPrefix(ref, "#") || len(stack) > 0) {
+			return l.resolveFileRef(ref, file, stack)
+		}
+		resolved := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			var err error
+			if resolved[k], err = l.resolveFileRefs(item, file, stack); err != nil {
+				return nil, err
+			}
+		}
+		return resolved, nil
+	}
+	return v, nil
+}
+
+// Read the value that a reference refers to, relative to the file which contains the reference.
+func (l *specLoader) resolveFileRef(ref, file string, stack []string) (interface{}, error) {
+	target, pointer := ref, ""
+	if i := strings.IndexByte(ref, '#'); i >= 0 {
+		target, pointer = ref[:i], ref[i+1:]
+	}
+	if target == "" {
+		target = file
+	} else if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(file), filepath.FromSlash(target))
+	}
+	key := target + "#" + pointer
+	for _, k := range stack {
+		if k == key {
+			return nil, errors.New("circular reference: " + ref)
+		}
+	}
+	v, err := l.read(target)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to resolve reference "+ref)
+	}
+	if v, err = resolvePointer(v, pointer); err != nil {
+		return nil, errors.WithMessage(err, "failed to resolve reference "+ref)
+	}
+	return l.resolveFileRefs(v, target, append(stack, key))
+}
+
+// Find the value at a JSON pointer (such as /components/schemas/Item) within a document.
+func resolvePointer(v interface{}, pointer string) (interface{}, error) {
+	if pointer == "" {
+		return v, nil
+	}
+	for _, token := range strings.Split(strings.TrimPrefix(pointer, "/"), "/") {
+		token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+		switch value := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = value[token]; !ok {
+				return nil, errors.New("no value at " + pointer)
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+			if err != nil || i < 0 || i >= len(value) {
+				return nil, errors.New("no value at " + pointer)
+			}
+			v = value[i]
+		default:
+			return nil, errors.New("no value at " + pointer)
+		}
+	}
+	return v, nil
+}
+
+// Replace a local reference (such as #/components/parameters/Limit) with the value it refers to.
+// References to references are followed up to 10 times, so that circular references are reported.
+func resolveLocalRef(doc map[string]interface{}, v interface{}) (interface{}, error) {
+	for i := 0; i < 10; i++ {
+		ref, ok := mapOf(v)["$ref"].(string)
+		if !ok || !strings.HasPrefix(ref, "#") {
+			return v, nil
+		}
+		resolved, err := resolvePointer(doc, ref[1:])
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to resolve reference "+ref)
+		}
+		v = resolved
+	}
+	return nil, errors.New(fmt.Sprint("reference is circular or nested too deeply: ", mapOf(v)["$ref"]))
+}
+
+// Convert a schema of the spec into a JSON Schema, using references to the schemas of the validator
+// and JSON Schema types in place of nullable.
+func toJSONSchema(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = toJSONSchema(item)
+		}
+		return s
+	case map[string]interface{}:
+		s := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			s[k] = toJSONSchema(item)
+		}
+		if ref, ok := s["$ref"].(string); ok && strings.HasPrefix(ref, componentSchemasRef) {
+			s["$ref"] = "{" + strings.TrimPrefix(ref, componentSchemasRef) + "}"
+		}
+		if nullable, _ := s["nullable"].(bool); nullable {
+			delete(s, "nullable")
+			if t, ok := s["type"].(string); ok {
+				s["type"] = []interface{}{t, "null"}
+			} else {
+				return map[string]interface{}{"anyOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+			}
+		}
+		return s
+	}
+	return v
+}
+
+// Convert the maps of a YAML document into maps with string keys, as in a JSON document.
+func normalizeYAML(v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		for i, item := range v {
+			v[i] = normalizeYAML(item)
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = normalizeYAML(item)
+		}
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			m[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return m
+	}
+	return v
+}
+
+// Convert a generic value into a typed value by encoding it as JSON. Nil values are ignored.
+func convertJSON(from, to interface{}) error {
+	if from == nil {
+		return nil
+	}
+	b, err := json.Marshal(from)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, to)
+}
+
+func mapOf(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
+
+func stringOf(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
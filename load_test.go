@@ -0,0 +1,249 @@
+package oas
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadOpenAPIServerVariables(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers string
+		target  string
+		wantErr string
+	}{
+		{"no servers", "[]", "/pets", ""},
+		{"plain url", "[{url: 'http://localhost/api'}]", "/api/pets", ""},
+		{"variables", `[{url: 'https://{region}.api.example.com/{basePath}',
+			variables: {region: {default: eu, enum: [eu, us]}, basePath: {default: v1}}}]`, "/v1/pets", ""},
+		{"variable in the path", "[{url: '/{version}/api', variables: {version: {default: v2}}}]", "/v2/api/pets", ""},
+		{"variable without a default", "[{url: 'https://{region}.example.com', variables: {region: {enum: [eu]}}}]", "",
+			"server variable has no default value: region"},
+		{"undefined variable", "[{url: 'https://{region}.example.com'}]", "", "server url has a variable which is not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := filepath.Join(t.TempDir(), "api.yaml")
+			err := os.WriteFile(file, []byte(`
+openapi: 3.0.3
+info: {title: Test, version: 1.0.0}
+servers: `+tt.servers+`
+paths:
+  /pets:
+    get:
+      operationId: getPets
+      responses: {'200': {description: ok}}
+`), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := func(Data) (interface{}, error) { return "ok", nil }
+			spec, _, err := LoadOpenAPI(file, map[string]HandlerFunc{"getPets": h}, nil, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, serve(spec, "GET", tt.target, nil, nil), 200)
+		})
+	}
+}
+
+// Write the files of a spec to a directory, returning the path of the first one.
+func writeSpecFiles(t *testing.T, files ...string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for i := 0; i+1 < len(files); i += 2 {
+		if err := os.WriteFile(filepath.Join(dir, files[i]), []byte(files[i+1]), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, files[0])
+}
+
+func TestLoadOpenAPIOperationHandlers(t *testing.T) {
+	h := func(Data) (interface{}, error) { return "ok", nil }
+	tests := []struct {
+		name     string
+		handlers map[string]HandlerFunc
+		paths    string
+		wantErr  string
+	}{
+		{"all handlers", map[string]HandlerFunc{"getPets": h, "addPet": h}, "", ""},
+		{"missing handler", map[string]HandlerFunc{"getPets": h}, "", "operations have no handler: addPet"},
+		{"unknown handler", map[string]HandlerFunc{"getPets": h, "addPet": h, "getOwners": h}, "",
+			"handlers have no operation: getOwners"},
+		{"missing and unknown handlers", map[string]HandlerFunc{"getPets": h, "getOwners": h}, "",
+			"operations have no handler: addPet; handlers have no operation: getOwners"},
+		{"no operationId", map[string]HandlerFunc{"getPets": h, "addPet": h},
+			"\n  /owners:\n    get:\n      responses: {'200': {description: ok}}", "operation has no operationId: GET /owners"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file := writeSpecFiles(t, "api.yaml", `
+openapi: 3.0.3
+info: {title: Test, version: 1.0.0}
+paths:
+  /pets:
+    get:
+      operationId: getPets
+      responses: {'200': {description: ok}}
+    post:
+      operationId: addPet
+      responses: {'200': {description: ok}}`+tt.paths+"\n")
+			_, _, err := LoadOpenAPI(file, tt.handlers, nil, nil)
+			if tt.wantErr == "" && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadOpenAPIReferences(t *testing.T) {
+	const spec = `
+openapi: 3.0.3
+info: {title: Test, version: 1.0.0}
+paths:
+  /pets:
+    post:
+      operationId: addPet
+      parameters: [{$ref: '#/components/parameters/Limit'}]
+      requestBody:
+        required: true
+        content: {application/json: {schema: {$ref: 'schemas.yaml#/Pet'}}}
+      responses: {'200': {description: ok}}
+components:
+  parameters:
+    Limit: {$ref: '#/components/parameters/%s'}
+    Count: {name: limit, in: query, schema: {type: integer}}
+    Loop: {$ref: '#/components/parameters/Limit'}
+`
+	tests := []struct {
+		name    string
+		files   []string
+		wantErr string
+	}{
+		{"file and local references", []string{"api.yaml", fmt.Sprintf(spec, "Count"),
+			"schemas.yaml", "Pet: {type: object, required: [name], properties: {name: {$ref: 'names.yaml#/Name'}}}",
+			"names.yaml", "Name: {type: string}"}, ""},
+		{"missing file", []string{"api.yaml", fmt.Sprintf(spec, "Count")}, "failed to resolve reference schemas.yaml#/Pet"},
+		{"missing value in a file", []string{"api.yaml", fmt.Sprintf(spec, "Count"), "schemas.yaml", "Other: {}"},
+			"no value at /Pet"},
+		{"circular file references", []string{"api.yaml", fmt.Sprintf(spec, "Count"),
+			"schemas.yaml", "Pet: {$ref: 'names.yaml#/Name'}", "names.yaml", "Name: {$ref: 'schemas.yaml#/Pet'}"},
+			"circular reference"},
+		{"missing local value", []string{"api.yaml", fmt.Sprintf(spec, "Other"), "schemas.yaml", "Pet: {}"},
+			"failed to resolve reference #/components/parameters/Other"},
+		{"circular local references", []string{"api.yaml", fmt.Sprintf(spec, "Loop"), "schemas.yaml", "Pet: {}"},
+			"reference is circular or nested too deeply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := func(Data) (interface{}, error) { return "ok", nil }
+			spec, _, err := LoadOpenAPI(writeSpecFiles(t, tt.files...), map[string]HandlerFunc{"addPet": h}, nil, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			headers := map[string]string{"Content-Type": "application/json"}
+			assertStatus(t, serve(spec, "POST", "/pets?limit=2", strings.NewReader(`{"name":"a"}`), headers), 200)
+			assertStatus(t, serve(spec, "POST", "/pets?limit=x", strings.NewReader(`{"name":"a"}`), headers), 400)
+			assertStatus(t, serve(spec, "POST", "/pets", strings.NewReader(`{"name":1}`), headers), 400)
+		})
+	}
+}
+
+func TestLoadOpenAPIParameters(t *testing.T) {
+	tests := []struct {
+		name       string
+		parameters string
+		target     string
+		status     int
+		wantErr    string
+	}{
+		{"path item parameter", "", "/pets?limit=x", 400, ""},
+		{"path item parameter is required", "", "/pets", 400, ""},
+		{"operation parameter overrides it", "[{name: limit, in: query, schema: {type: string}}]", "/pets?limit=x", 200, ""},
+		{"operation parameter is optional", "[{name: limit, in: query, schema: {type: string}}]", "/pets", 200, ""},
+		{"parameter in another location", "[{name: limit, in: header, schema: {type: string}}]", "/pets", 400, ""},
+		{"content parameter", "[{name: filter, in: query, content: {application/json: {schema: {type: object}}}}]", "", 0,
+			"parameters with content instead of a schema are not supported: query filter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parameters := tt.parameters
+			if parameters == "" {
+				parameters = "[]"
+			}
+			file := writeSpecFiles(t, "api.yaml", `
+openapi: 3.0.3
+info: {title: Test, version: 1.0.0}
+paths:
+  /pets:
+    parameters: [{name: limit, in: query, required: true, schema: {type: integer}}]
+    get:
+      operationId: getPets
+      parameters: `+parameters+`
+      responses: {'200': {description: ok}}
+`)
+			h := func(Data) (interface{}, error) { return "ok", nil }
+			spec, _, err := LoadOpenAPI(file, map[string]HandlerFunc{"getPets": h}, nil, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, serve(spec, "GET", tt.target, nil, nil), tt.status)
+		})
+	}
+}
+
+func TestToJSONSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema interface{}
+		want   interface{}
+	}{
+		{"nullable type", map[string]interface{}{"type": "string", "nullable": true},
+			map[string]interface{}{"type": []interface{}{"string", "null"}}},
+		{"not nullable", map[string]interface{}{"type": "string", "nullable": false},
+			map[string]interface{}{"type": "string", "nullable": false}},
+		{"nullable reference", map[string]interface{}{"$ref": "#/components/schemas/Pet", "nullable": true},
+			map[string]interface{}{"anyOf": []interface{}{map[string]interface{}{"$ref": "{Pet}"}, map[string]interface{}{"type": "null"}}}},
+		{"nested nullable property", map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+			"tags": map[string]interface{}{"type": "array", "nullable": true}}},
+			map[string]interface{}{"type": "object", "properties": map[string]interface{}{
+				"tags": map[string]interface{}{"type": []interface{}{"array", "null"}}}}},
+		{"list of schemas", []interface{}{map[string]interface{}{"$ref": "#/components/schemas/Pet"}},
+			[]interface{}{map[string]interface{}{"$ref": "{Pet}"}}},
+		{"other reference", map[string]interface{}{"$ref": "#/components/parameters/Limit"},
+			map[string]interface{}{"$ref": "#/components/parameters/Limit"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toJSONSchema(tt.schema); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,24 @@
+package oas
+
+func (o *openAPI) Use(middleware ...Middleware) {
+	o.middleware = append(o.middleware, middleware...)
+}
+
+func (e *endpointObject) Use(middleware ...Middleware) EndpointDeclaration {
+	e.middleware = append(e.middleware, middleware...)
+	return e
+}
+
+// Run the endpoint function through the middleware of the spec, then the middleware of the endpoint.
+// Panics within middleware are recovered in the same way as panics within the endpoint function.
+func (e *endpointObject) handle(d Data) (i interface{}, err error) {
+	defer e.recoverPanic(&err)
+	h := HandlerFunc(e.UserDefinedFunc)
+	for i := len(e.middleware) - 1; i >= 0; i-- {
+		h = e.middleware[i](h)
+	}
+	for i := len(e.spec.middleware) - 1; i >= 0; i-- {
+		h = e.spec.middleware[i](h)
+	}
+	return h(d)
+}
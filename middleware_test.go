@@ -0,0 +1,118 @@
+package oas
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tjbrockmeyer/oasm"
+)
+
+// Create middleware which records its name in the calls before calling the next handler.
+func recordingMiddleware(calls *[]string, name string) Middleware {
+	return func(next HandlerFunc) HandlerFunc {
+		return func(d Data) (interface{}, error) {
+			*calls = append(*calls, name)
+			return next(d)
+		}
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	spec := newTestSpec(t)
+	spec.Use(recordingMiddleware(&calls, "spec 1"), recordingMiddleware(&calls, "spec 2"))
+	spec.NewEndpoint("get", "GET", "/items", "", "", nil).
+		Parameter(oasm.InQuery, "page", "", false, map[string]interface{}{"type": "integer"}, reflect.Int).
+		Use(recordingMiddleware(&calls, "endpoint 1")).
+		Use(recordingMiddleware(&calls, "endpoint 2")).
+		MustDefine(func(Data) (interface{}, error) {
+			calls = append(calls, "handler")
+			return "ok", nil
+		})
+	// Middleware of the spec applies to endpoints which were defined beforehand.
+	spec.Use(recordingMiddleware(&calls, "spec 3"))
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		calls  []string
+	}{
+		{"valid request", "/api/items", 200, []string{"spec 1", "spec 2", "spec 3", "endpoint 1", "endpoint 2", "handler"}},
+		{"invalid request", "/api/items?page=x", 400, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			assertStatus(t, serve(spec, "GET", tt.target, nil, nil), tt.status)
+			if !reflect.DeepEqual(calls, tt.calls) {
+				t.Fatalf("expected the calls %v, got %v", tt.calls, calls)
+			}
+		})
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	var calls []string
+	spec := newTestSpec(t)
+	spec.Use(func(next HandlerFunc) HandlerFunc {
+		return func(d Data) (interface{}, error) {
+			if d.Req.Header.Get("X-Block") != "" {
+				return nil, NewProblem(403, "Blocked.")
+			}
+			return next(d)
+		}
+	})
+	spec.NewEndpoint("get", "GET", "/items", "", "", nil).
+		Response(200, "ok", nil).
+		Use(recordingMiddleware(&calls, "endpoint")).
+		MustDefine(func(Data) (interface{}, error) {
+			calls = append(calls, "handler")
+			return "ok", nil
+		})
+
+	assertStatus(t, serve(spec, "GET", "/api/items", nil, map[string]string{"X-Block": "1"}), 403)
+	if len(calls) != 0 {
+		t.Fatalf("expected the endpoint not to be called, got %v", calls)
+	}
+	assertStatus(t, serve(spec, "GET", "/api/items", nil, nil), 200)
+	if !reflect.DeepEqual(calls, []string{"endpoint", "handler"}) {
+		t.Fatalf("expected the endpoint to be called, got %v", calls)
+	}
+}
+
+func TestRecoverPanic(t *testing.T) {
+	panicking := func(next HandlerFunc) HandlerFunc {
+		return func(Data) (interface{}, error) { panic("middleware failed") }
+	}
+	tests := []struct {
+		name       string
+		middleware []Middleware
+		handler    HandlerFunc
+	}{
+		{"handler", nil, func(Data) (interface{}, error) { panic("handler failed") }},
+		{"middleware", []Middleware{panicking}, func(Data) (interface{}, error) { return "ok", nil }},
+		{"runtime error", nil, func(Data) (interface{}, error) {
+			var err error
+			return err.Error(), nil
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTestSpec(t)
+			var reported error
+			spec.SetResponseAndErrorHandler(func(_ Data, _ Response, err error) { reported = err })
+			spec.NewEndpoint("get", "GET", "/items", "", "", nil).Use(tt.middleware...).MustDefine(tt.handler)
+
+			w := serve(spec, "GET", "/api/items", nil, nil)
+			assertStatus(t, w, 500)
+			if strings.Contains(w.Body.String(), "failed") {
+				t.Fatalf("expected the panic not to be exposed: %s", w.Body.String())
+			}
+			if reported == nil || !strings.HasPrefix(reported.Error(), "a fatal error occurred") {
+				t.Fatalf("expected the panic to be reported, got %v", reported)
+			}
+		})
+	}
+}
@@ -0,0 +1,20 @@
+module github.com/tjbrockmeyer/oas/oaschi
+
+go 1.22
+
+require (
+	github.com/go-chi/chi/v5 v5.0.12
+	github.com/tjbrockmeyer/oas v0.0.0-00010101000000-000000000000
+)
+
+require (
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/tjbrockmeyer/oasm v1.0.0 // indirect
+	github.com/tjbrockmeyer/vjsonschema v1.0.0 // indirect
+	github.com/xeipuuv/gojsonpointer v0.0.0-20180127040702-4e3ac2762d5f // indirect
+	github.com/xeipuuv/gojsonreference v0.0.0-20180127040603-bd5ef7bd5415 // indirect
+	github.com/xeipuuv/gojsonschema v1.2.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/tjbrockmeyer/oas => ../
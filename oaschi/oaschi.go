@@ -0,0 +1,60 @@
+// Mount endpoints on a chi router, using its URL parameters as path parameters.
+package oaschi
+
+import (
+	"github.com/go-chi/chi/v5"
+	"github.com/tjbrockmeyer/oas"
+	"net/http"
+	"strings"
+)
+
+// Translate an endpoint path (see: oas.Endpoint.Settings) into a chi route pattern.
+// Parameters keep their regular expressions, and a final catch-all parameter (such as {name:.*}) becomes a wildcard.
+func Pattern(path string) string {
+	segments := oas.PathSegments(path)
+	var b strings.Builder
+	for i, segment := range segments {
+		b.WriteByte('/')
+		switch {
+		case segment.Param == "":
+			b.WriteString(segment.Static)
+		case segment.IsCatchAll() && i == len(segments)-1:
+			b.WriteString("*")
+		case segment.Pattern != "":
+			b.WriteString("{" + segment.Param + ":" + segment.Pattern + "}")
+		default:
+			b.WriteString("{" + segment.Param + "}")
+		}
+	}
+	return b.String()
+}
+
+// Create a RouteCreator which mounts endpoints on the router (such as a sub-router at the path of the server URL),
+// wrapped in oas.EndpointAttachingMiddleware and then the middleware, in order.
+// Endpoints whose paths differ only by the regular expressions of their parameters cannot both be mounted,
+// since catch-all parameters are matched without them. See: oas.PatternlessRouter
+func RouteCreator(router chi.Router, middleware ...func(http.Handler) http.Handler) oas.RouteCreator {
+	return func(endpoint oas.Endpoint, handler http.Handler) {
+		oas.PatternlessRouter(endpoint)
+		method, path, _ := endpoint.Settings()
+		segments := oas.PathSegments(path)
+		for i := len(middleware) - 1; i >= 0; i-- {
+			handler = middleware[i](handler)
+		}
+		handler = oas.EndpointAttachingMiddleware(endpoint)(handler)
+		router.Method(strings.ToUpper(method), Pattern(path), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			params := make(map[string]string)
+			for i, segment := range segments {
+				if segment.Param == "" {
+					continue
+				}
+				if segment.IsCatchAll() && i == len(segments)-1 {
+					params[segment.Param] = chi.URLParam(r, "*")
+				} else {
+					params[segment.Param] = chi.URLParam(r, segment.Param)
+				}
+			}
+			handler.ServeHTTP(w, oas.WithPathParams(r, params))
+		}))
+	}
+}
@@ -0,0 +1,65 @@
+// Mount endpoints on an echo instance or group, using its path parameters as path parameters.
+package oasecho
+
+import (
+	"github.com/labstack/echo/v4"
+	"github.com/tjbrockmeyer/oas"
+	"net/http"
+	"strings"
+)
+
+// The methods shared by *echo.Echo and *echo.Group for adding routes.
+type Router interface {
+	Add(method, path string, handler echo.HandlerFunc, middleware ...echo.MiddlewareFunc) *echo.Route
+}
+
+// Translate an endpoint path (see: oas.Endpoint.Settings) into an echo route path.
+// Parameters become named parameters, and a final catch-all parameter (such as {name:.*}) becomes a wildcard.
+// Other regular expressions are checked by the endpoint.
+func Pattern(path string) string {
+	segments := oas.PathSegments(path)
+	var b strings.Builder
+	for i, segment := range segments {
+		b.WriteByte('/')
+		switch {
+		case segment.Param == "":
+			b.WriteString(segment.Static)
+		case segment.IsCatchAll() && i == len(segments)-1:
+			b.WriteString("*")
+		default:
+			b.WriteString(":" + segment.Param)
+		}
+	}
+	return b.String()
+}
+
+// Create a RouteCreator which mounts endpoints on the router (such as a group at the path of the server URL),
+// wrapped in oas.EndpointAttachingMiddleware and then the middleware, in order.
+// Endpoints whose paths differ only by the regular expressions of their parameters cannot both be mounted,
+// since the router matches parameters without them. See: oas.PatternlessRouter
+func RouteCreator(router Router, middleware ...func(http.Handler) http.Handler) oas.RouteCreator {
+	return func(endpoint oas.Endpoint, handler http.Handler) {
+		oas.PatternlessRouter(endpoint)
+		method, path, _ := endpoint.Settings()
+		segments := oas.PathSegments(path)
+		for i := len(middleware) - 1; i >= 0; i-- {
+			handler = middleware[i](handler)
+		}
+		handler = oas.EndpointAttachingMiddleware(endpoint)(handler)
+		router.Add(strings.ToUpper(method), Pattern(path), func(c echo.Context) error {
+			params := make(map[string]string)
+			for i, segment := range segments {
+				if segment.Param == "" {
+					continue
+				}
+				if segment.IsCatchAll() && i == len(segments)-1 {
+					params[segment.Param] = c.Param("*")
+				} else {
+					params[segment.Param] = c.Param(segment.Param)
+				}
+			}
+			handler.ServeHTTP(c.Response(), oas.WithPathParams(c.Request(), params))
+			return nil
+		})
+	}
+}
@@ -0,0 +1,55 @@
+// Mount endpoints on a gin engine or router group, using its path parameters as path parameters.
+package oasgin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tjbrockmeyer/oas"
+	"net/http"
+	"strings"
+)
+
+// Translate an endpoint path (see: oas.Endpoint.Settings) into a gin route path.
+// Parameters become named parameters, and a final catch-all parameter (such as {name:.*}) becomes a catch-all.
+// Other regular expressions are checked by the endpoint.
+func Pattern(path string) string {
+	segments := oas.PathSegments(path)
+	var b strings.Builder
+	for i, segment := range segments {
+		b.WriteByte('/')
+		switch {
+		case segment.Param == "":
+			b.WriteString(segment.Static)
+		case segment.IsCatchAll() && i == len(segments)-1:
+			b.WriteString("*" + segment.Param)
+		default:
+			b.WriteString(":" + segment.Param)
+		}
+	}
+	return b.String()
+}
+
+// Create a RouteCreator which mounts endpoints on the router (such as a group at the path of the server URL),
+// wrapped in oas.EndpointAttachingMiddleware and then the middleware, in order.
+// Endpoints whose paths differ only by the regular expressions of their parameters cannot both be mounted,
+// since the router matches parameters without them. See: oas.PatternlessRouter
+func RouteCreator(router gin.IRoutes, middleware ...func(http.Handler) http.Handler) oas.RouteCreator {
+	return func(endpoint oas.Endpoint, handler http.Handler) {
+		oas.PatternlessRouter(endpoint)
+		method, path, _ := endpoint.Settings()
+		segments := oas.PathSegments(path)
+		for i := len(middleware) - 1; i >= 0; i-- {
+			handler = middleware[i](handler)
+		}
+		handler = oas.EndpointAttachingMiddleware(endpoint)(handler)
+		router.Handle(strings.ToUpper(method), Pattern(path), func(c *gin.Context) {
+			params := make(map[string]string)
+			for _, segment := range segments {
+				if segment.Param != "" {
+					// Catch-all parameters include the leading slash.
+					params[segment.Param] = strings.TrimPrefix(c.Param(segment.Param), "/")
+				}
+			}
+			handler.ServeHTTP(c.Writer, oas.WithPathParams(c.Request, params))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package oasgin
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tjbrockmeyer/oas"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRouteCreator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	spec, _, err := oas.NewOpenAPI("Test", "", "http://localhost/api", "1.0.0", t.TempDir(), nil, RouteCreator(router.Group("/api")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	echoParam := func(name string) oas.HandlerFunc {
+		return func(d oas.Data) (interface{}, error) { return d.Params[name], nil }
+	}
+	spec.NewEndpoint("getPet", "GET", "/pets/{id:[0-9]+}", "", "", nil).
+		Parameter("path", "id", "", true, map[string]interface{}{"type": "integer"}, reflect.Int).
+		Response(200, "The id.", nil).
+		MustDefine(echoParam("id"))
+	spec.NewEndpoint("getFile", "GET", "/files/{path:.+}", "", "", nil).
+		Parameter("path", "path", "", true, map[string]interface{}{"type": "string"}, reflect.String).
+		Response(200, "The path.", nil).
+		MustDefine(echoParam("path"))
+
+	_, err = spec.NewEndpoint("getPetByName", "GET", "/pets/{name:[a-z]+}", "", "", nil).
+		Parameter("path", "name", "", true, map[string]interface{}{"type": "string"}, reflect.String).
+		Define(echoParam("name"))
+	if err == nil || !strings.Contains(err.Error(), "differs only by the regular expressions") {
+		t.Fatalf("expected a route which differs only by its regular expression to be rejected, got %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		body   string
+	}{
+		{"parameter", "/api/pets/12", 200, "12"},
+		{"parameter which does not match its pattern", "/api/pets/abc", 404, ""},
+		{"catch-all parameter", "/api/files/docs/readme.md", 200, `"docs/readme.md"`},
+		{"unknown path", "/api/owners", 404, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.target, nil))
+			if w.Code != tt.status {
+				t.Fatalf("expected status %v, got %v: %s", tt.status, w.Code, w.Body.String())
+			}
+			if tt.body != "" && strings.TrimSpace(w.Body.String()) != tt.body {
+				t.Fatalf("expected the body %s, got %s", tt.body, w.Body.String())
+			}
+		})
+	}
+}
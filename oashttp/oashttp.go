@@ -0,0 +1,55 @@
+// Mount endpoints on a net/http ServeMux, using its path wildcards as path parameters.
+// Path patterns require the main module to declare Go 1.22 or later.
+package oashttp
+
+import (
+	"github.com/tjbrockmeyer/oas"
+	"net/http"
+	"strings"
+)
+
+// Translate an endpoint path (see: oas.Endpoint.Settings) into a ServeMux path pattern.
+// Parameters become wildcards, and a final catch-all parameter (such as {name:.*}) matches the rest of the path.
+// Other regular expressions are checked by the endpoint.
+func Pattern(path string) string {
+	segments := oas.PathSegments(path)
+	var b strings.Builder
+	for i, segment := range segments {
+		b.WriteByte('/')
+		switch {
+		case segment.Param == "":
+			b.WriteString(segment.Static)
+		case segment.IsCatchAll() && i == len(segments)-1:
+			b.WriteString("{" + segment.Param + "...}")
+		default:
+			b.WriteString("{" + segment.Param + "}")
+		}
+	}
+	return b.String()
+}
+
+// Create a RouteCreator which mounts endpoints on the mux under the prefix (such as the path of the server URL),
+// wrapped in oas.EndpointAttachingMiddleware and then the middleware, in order.
+// Endpoints whose paths differ only by the regular expressions of their parameters cannot both be mounted,
+// since the router matches parameters without them. See: oas.PatternlessRouter
+func RouteCreator(mux *http.ServeMux, prefix string, middleware ...func(http.Handler) http.Handler) oas.RouteCreator {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return func(endpoint oas.Endpoint, handler http.Handler) {
+		oas.PatternlessRouter(endpoint)
+		method, path, _ := endpoint.Settings()
+		segments := oas.PathSegments(path)
+		for i := len(middleware) - 1; i >= 0; i-- {
+			handler = middleware[i](handler)
+		}
+		handler = oas.EndpointAttachingMiddleware(endpoint)(handler)
+		mux.Handle(strings.ToUpper(method)+" "+prefix+Pattern(path), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			params := make(map[string]string)
+			for _, segment := range segments {
+				if segment.Param != "" {
+					params[segment.Param] = r.PathValue(segment.Param)
+				}
+			}
+			handler.ServeHTTP(w, oas.WithPathParams(r, params))
+		}))
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 type endpoint struct {
 	doc      oasm.Operation
+	options  map[string]interface{}
 	function oas.HandlerFunc
 }
 
@@ -17,23 +18,76 @@ func (e *endpoint) Version(int) oas.EndpointDeclaration {
 	return e
 }
 
+func (e *endpoint) Set(key string, value interface{}) oas.EndpointDeclaration {
+	e.options[key] = value
+	return e
+}
+
 func (e *endpoint) Parameter(in string, name string, description string, required bool, schema interface{}, kind reflect.Kind) oas.EndpointDeclaration {
 	return e
 }
 
+func (e *endpoint) ParameterStyle(in, name, style string, explode bool) oas.EndpointDeclaration {
+	return e
+}
+
 func (e *endpoint) RequestBody(description string, required bool, schema interface{}, object interface{}) oas.EndpointDeclaration {
 	return e
 }
 
+func (e *endpoint) RequestBodyContent(mimeType, description string, required bool, schema interface{}, object interface{}) oas.EndpointDeclaration {
+	return e
+}
+
 func (e *endpoint) Response(code int, description string, schema interface{}) oas.EndpointDeclaration {
 	return e
 }
 
+func (e *endpoint) ResponseRange(class int, description string, schema interface{}) oas.EndpointDeclaration {
+	return e
+}
+
+func (e *endpoint) DefaultResponse(description string, schema interface{}) oas.EndpointDeclaration {
+	return e
+}
+
+func (e *endpoint) ResponseOf(code int, description string, object interface{}) oas.EndpointDeclaration {
+	return e
+}
+
+func (e *endpoint) ResponseMediaTypes(code int, mimeTypes ...string) oas.EndpointDeclaration {
+	return e
+}
+
+func (e *endpoint) ResponseHeader(code int, name, description string, required bool, schema interface{}) oas.EndpointDeclaration {
+	return e
+}
+
+func (e *endpoint) MapError(error, int, string, oas.ErrorBodyFunc) oas.EndpointDeclaration {
+	return e
+}
+
+func (e *endpoint) MapErrorType(interface{}, int, string, oas.ErrorBodyFunc) oas.EndpointDeclaration {
+	return e
+}
+
+func (e *endpoint) ResponseValidation(oas.ResponseValidationMode, float64) oas.EndpointDeclaration {
+	return e
+}
+
+func (e *endpoint) Use(...oas.Middleware) oas.EndpointDeclaration {
+	return e
+}
+
 func (e *endpoint) Deprecate(comment string) oas.EndpointDeclaration {
 	return e
 }
 
-func (e *endpoint) Security(name string, scopes []string) oas.EndpointDeclaration {
+func (e *endpoint) Security(nameToScopesMapping map[string][]string) oas.EndpointDeclaration {
+	return e
+}
+
+func (e *endpoint) NoSecurity() oas.EndpointDeclaration {
 	return e
 }
 
@@ -51,12 +105,16 @@ func (e *endpoint) Doc() *oasm.Operation {
 	return &e.doc
 }
 
+func (e *endpoint) Get(key string) interface{} {
+	return e.options[key]
+}
+
 func (e *endpoint) Settings() (method, path string, version int) {
 	return "", "", 0
 }
 
-func (e *endpoint) SecurityMapping() map[*oasm.SecurityRequirement]oasm.SecurityScheme {
-	return map[*oasm.SecurityRequirement]oasm.SecurityScheme{}
+func (e *endpoint) SecurityMapping() []map[string]oasm.SecurityScheme {
+	return []map[string]oasm.SecurityScheme{}
 }
 
 func (e *endpoint) UserDefinedFunc(data oas.Data) (interface{}, error) {
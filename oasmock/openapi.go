@@ -3,6 +3,8 @@ package oasmock
 import (
 	"github.com/tjbrockmeyer/oas"
 	"github.com/tjbrockmeyer/oasm"
+	"io"
+	"net/http"
 	"strings"
 )
 
@@ -21,6 +23,38 @@ func (o *openAPI) DefaultJSONIndent() int {
 	return 0
 }
 
+func (o *openAPI) SetParamDecoder(string, oas.ParamDecoder) {}
+
+func (o *openAPI) SetParamTypeDecoder(interface{}, string, oas.ParamDecoder) {}
+
+func (o *openAPI) SetMaxRequestBodySize(int64) {}
+
+func (o *openAPI) SetResponseEncoder(string, oas.ResponseEncoder) {}
+
+func (o *openAPI) MapError(error, int, string, oas.ErrorBodyFunc) error {
+	return nil
+}
+
+func (o *openAPI) MapErrorType(interface{}, int, string, oas.ErrorBodyFunc) error {
+	return nil
+}
+
+func (o *openAPI) SetResponseValidation(oas.ResponseValidationMode, float64) {}
+
+func (o *openAPI) SetResponseValidationHandler(oas.ResponseValidationHandler) {}
+
+func (o *openAPI) SetUndeclaredStatusMode(oas.UndeclaredStatusMode) {}
+
+func (o *openAPI) SetUndeclaredStatusHandler(oas.UndeclaredStatusHandler) {}
+
+func (o *openAPI) SetSecurityHandler(string, oas.SecurityHandler) {}
+
+func (o *openAPI) Use(...oas.Middleware) {}
+
+func (o *openAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	http.NotFound(w, r)
+}
+
 func (o *openAPI) SetResponseAndErrorHandler(oas.ResponseAndErrorHandler) {}
 
 func (o *openAPI) NewEndpoint(operationId, method, path, summary, description string, tags []string) oas.EndpointDeclaration {
@@ -36,6 +70,7 @@ func (o *openAPI) NewEndpoint(operationId, method, path, summary, description st
 			},
 			Security: make([]oasm.SecurityRequirement, 0, 1),
 		},
+		options: make(map[string]interface{}),
 	}
 	o.endpoints[operationId] = e
 	return e
@@ -45,6 +80,36 @@ func (o *openAPI) Endpoints() map[string]oas.Endpoint {
 	return o.endpoints
 }
 
+func (o *openAPI) SchemaOf(object interface{}) (interface{}, error) {
+	return map[string]interface{}{}, nil
+}
+
+func (o *openAPI) SetSwaggerUIOptions(oas.SwaggerUIOptions) {}
+
+func (o *openAPI) SetDocsUI(*oas.DocsUI) {}
+
+func (o *openAPI) SetPrettySpec(bool) {}
+
+func (o *openAPI) ExportHTML(io.Writer) error {
+	return nil
+}
+
+func (o *openAPI) ExportMarkdown(io.Writer) error {
+	return nil
+}
+
+func (o *openAPI) SetOpenAPIVersion(version oas.OpenAPIVersion) {
+	o.doc.OpenApi = string(version)
+}
+
+func (o *openAPI) AddWebhook(string, string, oasm.Operation) {}
+
+func (o *openAPI) Validate() error {
+	return nil
+}
+
+func (o *openAPI) ValidateOnStart(oas.SpecValidationMode) {}
+
 func (o *openAPI) Save() error {
 	return nil
 }
@@ -5,12 +5,13 @@ import (
 	"github.com/pkg/errors"
 	"github.com/tjbrockmeyer/oasm"
 	"github.com/tjbrockmeyer/vjsonschema"
+	"io"
 	"net/http"
 	"net/url"
-	"path"
+	"reflect"
 	"regexp"
-	"runtime"
 	"strings"
+	"sync/atomic"
 )
 
 var pathRegex = regexp.MustCompile(`/(?:[^{][^/]*|{(\w+)(?::(.*?[^\\]))?})`)
@@ -21,7 +22,11 @@ type RouteCreator func(Endpoint, http.Handler)
 type ResponseAndErrorHandler func(Data, Response, error)
 
 type OpenAPI interface {
+	// Serve requests using the routes of the defined endpoints. See: NewOpenAPI
+	http.Handler
 	// Get the API documentation for reading or modification.
+	// Changes are reflected in the spec served by the file server the next time it is requested,
+	// so the doc should be modified through a new call rather than a pointer which was kept while the spec was served.
 	Doc() *oasm.OpenAPIDoc
 	// Function to handle responses and/or errors that come from an endpoint function call.
 	// The default implementation prints any errors to stdout.
@@ -30,29 +35,129 @@ type OpenAPI interface {
 	SetDefaultJSONIndent(int)
 	// Get Indent level of JSON responses. (Default: 2) A level of 0 will print condensed JSON.
 	DefaultJSONIndent() int
+	// Set the decoder used for string parameters (and their items or properties) with the schema format.
+	// Decoders are run after validation, and failures are reported as parameter type errors.
+	// By default, date-time and date are decoded into time.Time, duration into time.Duration, and uuid into UUID.
+	SetParamDecoder(format string, decoder ParamDecoder)
+	// Set the schema format of parameters bound to request struct fields of the Go type of `object` (see: Handle),
+	// and the decoder of the format, which must return values of the type. The decoder is kept if it is nil.
+	// By default, time.Time has the format date-time, time.Duration has duration, and UUID has uuid.
+	SetParamTypeDecoder(object interface{}, format string, decoder ParamDecoder)
+	// Set the maximum size of request bodies in bytes, or remove the limit if it is 0. (Default: 0)
+	// Larger request bodies receive a 413 problem, which is documented by endpoints defined while a limit is set.
+	SetMaxRequestBodySize(size int64)
+	// Set the encoder used for responses of the media type, or remove it if the encoder is nil.
+	// Encoders for JSON, YAML, XML, MessagePack, CSV, and plain text are set by default.
+	SetResponseEncoder(mimeType string, encoder ResponseEncoder)
+	// Map errors returned by handlers which match the target (using errors.Is) to responses with the status code.
+	// The response body is created by the body function, or is a Problem with the error message as its detail if it is nil.
+	// Mapped statuses are documented on endpoints which are defined afterwards, and error responses
+	// without a declared schema are documented as problems.
+	// Mappings are checked in the order they were added, after those of the endpoint.
+	MapError(target error, status int, description string, body ErrorBodyFunc) error
+	// Map errors returned by handlers which match the type of the target (using errors.As) to responses with the status code.
+	// The target is a value of the error type, such as (*MyError)(nil), or a pointer to one, such as new(MyInterface).
+	// See: MapError
+	MapErrorType(target interface{}, status int, description string, body ErrorBodyFunc) error
+	// Set the response validation mode, and the fraction of responses (from 0 to 1) which are validated.
+	// (Default: ResponseValidationLog, 1) Endpoints may override these settings.
+	SetResponseValidation(mode ResponseValidationMode, sampleRate float64)
+	// Set a function to receive response validation failures, such as for alerting on differences from the spec.
+	SetResponseValidationHandler(ResponseValidationHandler)
+	// Set what happens when an endpoint responds with a status code which is not documented by its operation,
+	// either by the code itself, its range, or the default response. (Default: UndeclaredStatusAllow)
+	// Problems are declared like any other response, so the default response must be declared for unexpected errors.
+	SetUndeclaredStatusMode(UndeclaredStatusMode)
+	// Set a function to receive the status codes of responses which were not documented by their operation.
+	// It is not called when the mode is UndeclaredStatusAllow.
+	SetUndeclaredStatusHandler(UndeclaredStatusHandler)
+	// Set the handler which authenticates requests for the security scheme of the name in Components.SecuritySchemes,
+	// or remove it if the handler is nil.
+	// Requests are authenticated before they are parsed, and must satisfy all of the schemes
+	// of any one security requirement of their operation, or of the spec if the operation has none.
+	// Security requirements are enforced only by endpoints which are defined after a handler has been set,
+	// and every scheme which they require must then have a handler. Otherwise, requirements are only documented.
+	// Credentials are read according to the scheme: apiKey from its header, query parameter, or cookie,
+	// http basic and bearer from the Authorization header, and oauth2 and openIdConnect as bearer tokens.
+	// Failed requests receive a 401 or 403 problem, and the principals are set on Data for successful requests.
+	SetSecurityHandler(schemeName string, handler SecurityHandler)
+	// Add middleware which runs for every endpoint after the request has been authenticated, parsed, and validated,
+	// including endpoints which were defined beforehand.
+	// Middleware of the OpenAPI runs in the order it was added, and wraps the middleware of each endpoint.
+	// Middleware may respond without calling the next function to stop the chain.
+	Use(middleware ...Middleware)
 	// Create a new endpoint for your API, complete with documentation.
 	NewEndpoint(operationId, method, path, summary, description string, tags []string) EndpointDeclaration
 	// Get all endpoints mapped by their operation ids.
 	Endpoints() map[string]Endpoint
+	// Generate a JSON Schema from the Go type of the object, registering named struct types in the spec.
+	SchemaOf(object interface{}) (interface{}, error)
+	// Change the settings of the Swagger UI which is served by the file server of the spec.
+	// See: DefaultSwaggerUIOptions
+	SetSwaggerUIOptions(options SwaggerUIOptions)
+	// Change the documentation UI which is served by the file server of the spec, such as ReDocUI or RapiDocUI.
+	// The Swagger UI is served if the UI is nil.
+	SetDocsUI(ui *DocsUI)
+	// Set whether the spec served by the file server is indented using the default JSON indent.
+	// The spec is served as openapi.json and openapi.yaml, with an ETag for conditional requests.
+	SetPrettySpec(pretty bool)
+	// Write a self-contained HTML reference of the API, which shows its operations grouped by tag,
+	// along with their parameters, request bodies, responses, and examples, and the schemas of the spec.
+	ExportHTML(w io.Writer) error
+	// Write a Markdown reference of the API. See: ExportHTML
+	ExportMarkdown(w io.Writer) error
+	// Set the version of OpenAPI which the spec is written in. (Default: OpenAPIVersion30)
+	// Schemas are documented using JSON Schema, and are converted for OpenAPI 3.0 when the spec is served.
+	SetOpenAPIVersion(version OpenAPIVersion)
+	// Document a webhook of the API, which is a request that the API sends to its clients, such as to notify them of events.
+	// Webhooks are written to the webhooks field of a 3.1 spec, or to x-webhooks in a 3.0 spec.
+	AddWebhook(name, method string, operation oasm.Operation)
+	// Check the spec against the schema of its version of OpenAPI, and for problems which the schema cannot find,
+	// such as undeclared tags, undefined security schemes, references to missing schemas, and undocumented path parameters.
+	// The error is SpecProblems if the spec has problems, which holds all of them with JSON Pointers to their locations.
+	Validate() error
+	// Validate the spec, logging any problems, and panicking if the mode is SpecValidationFail.
+	// This should be called once all endpoints have been defined, such as right before the server is started.
+	ValidateOnStart(mode SpecValidationMode)
 }
 
 type openAPI struct {
-	doc                     oasm.OpenAPIDoc
-	jsonIndent              int
-	responseAndErrorHandler ResponseAndErrorHandler
-	validatorBuilder        vjsonschema.Builder
-	validator               vjsonschema.Validator
-	routeCreator            RouteCreator
-	endpoints               map[string]Endpoint
-	fileServer              *customFileServer
-	url                     *url.URL
+	doc                       oasm.OpenAPIDoc
+	jsonIndent                int
+	responseAndErrorHandler   ResponseAndErrorHandler
+	validatorBuilder          vjsonschema.Builder
+	validator                 vjsonschema.Validator
+	routeCreator              RouteCreator
+	endpoints                 map[string]Endpoint
+	schemaTypes               map[reflect.Type]string
+	encoders                  map[string]ResponseEncoder
+	paramDecoders             map[string]ParamDecoder
+	paramTypeFormats          map[reflect.Type]string
+	maxRequestBodySize        int64
+	errorMappings             []errorMapping
+	responseValidation        responseValidation
+	responseValidationHandler ResponseValidationHandler
+	undeclaredStatusMode      UndeclaredStatusMode
+	undeclaredStatusHandler   UndeclaredStatusHandler
+	securityHandlers          map[string]SecurityHandler
+	middleware                []Middleware
+	routes                    []route
+	patternlessRouter         bool
+	swaggerUIOptions          SwaggerUIOptions
+	docsUI                    *DocsUI
+	prettySpec                bool
+	docGeneration             atomic.Uint64
+	webhooks                  map[string]map[string]oasm.Operation
+	responseRanges            map[operationKey]map[int]oasm.Response
+	fileServer                *customFileServer
+	url                       *url.URL
 }
 
 // Create a new OpenAPI Specification with JSON Schemas and a Swagger UI.
 //
 // This will:
 //   - Generate an OpenAPI Specification for the API inside the provided directory.
-//   - Create documentation and routes (via param:routeCreator) for all endpoints passed as arguments.
+//   - Create documentation and routes (via param:routeCreator and the spec's own route table) for all endpoints passed as arguments.
 //   - Add all definitions from a provided JSON Schema file into the generated spec.
 //   - Generate a Swagger UI in the target directory, returning a handler which can be used to mount the file server.
 //   - Add middleware for authorization or other needs to every endpoint, with the endpoint itself as context.
@@ -66,7 +171,8 @@ type openAPI struct {
 //   dir          - A directory for hosting the spec, schemas, and SwaggerUI - typically a folder like ./public
 //   schemasDir   - A path to a directory of valid JSON Schemas for objects to be used by the API
 //   tags         - A list of Tag objects for describing the sections of the API which hold endpoints
-//   routeCreator - A function which can add middleware and mount an endpoint at an http path
+//   routeCreator - A function which can add middleware and mount an endpoint at an http path, which may be nil
+//                  if the spec itself is used as the http.Handler of the API
 //
 // Returns:
 //   spec       - The specification object
@@ -76,6 +182,29 @@ func NewOpenAPI(
 	title, description, serverUrl, version, schemasDir string,
 	tags []oasm.Tag, routeCreator RouteCreator,
 ) (spec OpenAPI, fileServer http.Handler, err error) {
+	o, err := newOpenAPI(title, description, serverUrl, version, tags, routeCreator)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := o.validatorBuilder.AddDir(schemasDir); err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to read the schema directory")
+	}
+	if err := o.addSchemas(); err != nil {
+		return nil, nil, err
+	}
+
+	if fileServer, err = o.newFileServer(); err != nil {
+		return nil, nil, err
+	}
+	return o, fileServer, nil
+}
+
+// Create a new OpenAPI Specification without any schemas or endpoints.
+func newOpenAPI(
+	title, description, serverUrl, version string,
+	tags []oasm.Tag, routeCreator RouteCreator,
+) (*openAPI, error) {
 	o := &openAPI{
 		doc: oasm.OpenAPIDoc{
 			OpenApi: "3.0.0",
@@ -96,50 +225,138 @@ func NewOpenAPI(
 		validatorBuilder: vjsonschema.NewBuilder(),
 		routeCreator:     routeCreator,
 		endpoints:        make(map[string]Endpoint),
+		schemaTypes:      make(map[reflect.Type]string),
+		encoders:         defaultResponseEncoders(),
+		paramDecoders:    defaultParamDecoders(),
+		securityHandlers: make(map[string]SecurityHandler),
+		swaggerUIOptions: DefaultSwaggerUIOptions(),
+		responseValidation: responseValidation{
+			mode:       ResponseValidationLog,
+			sampleRate: 1,
+		},
+		paramTypeFormats: defaultParamTypeFormats(),
 	}
 	if parsedUrl, err := url.Parse(serverUrl); err != nil {
-		return nil, nil, err
+		return nil, err
 	} else {
 		o.url = parsedUrl
 	}
+	return o, nil
+}
 
-	if err := o.validatorBuilder.AddDir(schemasDir); err != nil {
-		return nil, nil, errors.WithMessage(err, "failed to read the schema directory")
-	}
-
+// Add the schemas of the validator builder to the components of the doc, along with the problem schema.
+func (o *openAPI) addSchemas() error {
 	if o.doc.Components.Schemas == nil {
 		o.doc.Components.Schemas = make(map[string]interface{})
 	}
 	for k, s := range o.validatorBuilder.GetSchemas() {
 		o.doc.Components.Schemas[k] = json.RawMessage(vjsonschema.SchemaRefReplace(s, refNameToSwaggerRef))
 	}
+	o.docChanged()
+	return o.registerProblemSchema()
+}
 
-	_, filePath, _, ok := runtime.Caller(0)
-	if !ok {
-		return nil, nil, err
+// Create the file server for the documentation UI and the spec.
+func (o *openAPI) newFileServer() (http.Handler, error) {
+	if _, err := o.currentDocsUI(); err != nil {
+		return nil, errors.WithMessage(err, "failed to read the Swagger UI files")
 	}
-	swaggerDist := path.Join(path.Dir(filePath), "swagger-dist")
-	d := http.Dir(swaggerDist)
-	fileServer = &customFileServer{
-		dir:        d,
-		fileServer: http.FileServer(d),
-		o:          o,
-	}
-	return o, fileServer, nil
+	return &customFileServer{o: o, spec: &specFiles{o: o}}, nil
 }
 
 func (o *openAPI) Doc() *oasm.OpenAPIDoc {
+	o.docChanged()
 	return &o.doc
 }
 
+// Marshal the doc into JSON, including the parts of the spec which cannot be represented by oasm.
+func (o *openAPI) docJSON() ([]byte, error) {
+	b, err := json.Marshal(o.doc)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = o.addOperationFields(b); err != nil {
+		return nil, err
+	}
+	return o.convertForVersion(b)
+}
+
+// Add the parts of the operations of the doc to its JSON encoding which oasm.Operation cannot represent.
+func (o *openAPI) addOperationFields(b []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	findOperation := func(path, method string) (map[string]interface{}, error) {
+		if doc == nil {
+			if err := json.Unmarshal(b, &doc); err != nil {
+				return nil, errors.WithMessage(err, "failed to read the spec for adding operation fields")
+			}
+		}
+		paths, _ := doc["paths"].(map[string]interface{})
+		pathItem, _ := paths[path].(map[string]interface{})
+		for m, operation := range pathItem {
+			if operation, ok := operation.(map[string]interface{}); ok && strings.EqualFold(m, method) {
+				return operation, nil
+			}
+		}
+		return nil, nil
+	}
+	for key, ranges := range o.responseRanges {
+		operation, err := findOperation(key.path, key.method)
+		if err != nil {
+			return nil, err
+		}
+		addResponseRanges(operation, ranges)
+	}
+	for _, endpoint := range o.endpoints {
+		e, ok := endpoint.(*endpointObject)
+		if !ok || !e.optsOutOfSecurity() {
+			continue
+		}
+		operation, err := findOperation(e.swaggerPath, e.method)
+		if err != nil {
+			return nil, err
+		}
+		if operation != nil {
+			operation["security"] = []interface{}{}
+		}
+	}
+	if doc == nil {
+		return b, nil
+	}
+	return json.Marshal(doc)
+}
+
 func (o *openAPI) SetDefaultJSONIndent(i int) {
 	o.jsonIndent = i
+	o.docChanged()
 }
 
 func (o *openAPI) DefaultJSONIndent() int {
 	return o.jsonIndent
 }
 
+func (o *openAPI) SetParamDecoder(format string, decoder ParamDecoder) {
+	if decoder == nil {
+		delete(o.paramDecoders, format)
+	} else {
+		o.paramDecoders[format] = decoder
+	}
+}
+
+func (o *openAPI) SetParamTypeDecoder(object interface{}, format string, decoder ParamDecoder) {
+	o.paramTypeFormats[reflect.TypeOf(object)] = format
+	if decoder != nil {
+		o.paramDecoders[format] = decoder
+	}
+}
+
+func (o *openAPI) SetResponseEncoder(mimeType string, encoder ResponseEncoder) {
+	if encoder == nil {
+		delete(o.encoders, mimeType)
+	} else {
+		o.encoders[mimeType] = encoder
+	}
+}
+
 func (o *openAPI) SetResponseAndErrorHandler(reh ResponseAndErrorHandler) {
 	o.responseAndErrorHandler = reh
 }
@@ -157,15 +374,20 @@ func (o *openAPI) NewEndpoint(operationId, method, path, summary, description st
 			},
 			Security: make([]oasm.SecurityRequirement, 0, 1),
 		},
-		path:               path,
-		method:             strings.ToLower(method),
-		bodyType:           nil,
-		query:              make([]typedParameter, 0, 3),
-		params:             make(map[int]typedParameter, 3),
-		headers:            make([]typedParameter, 0, 3),
-		reqSchemaName:      "endpoint_" + operationId + "_request",
-		responseSchemaRefs: make(map[int]string),
-		spec:               o,
+		path:                    path,
+		method:                  strings.ToLower(method),
+		bodies:                  make(map[string]*requestBodyMedia),
+		query:                   make([]typedParameter, 0, 3),
+		params:                  make(map[int]typedParameter, 3),
+		headers:                 make([]typedParameter, 0, 3),
+		cookies:                 make([]typedParameter, 0, 3),
+		reqSchemaName:           "endpoint_" + operationId + "_request",
+		responseSchemaRefs:      make(map[int]string),
+		responseRanges:          make(map[int]oasm.Response),
+		responseRangeSchemaRefs: make(map[int]string),
+		responseHeaders:         make(map[int][]string),
+		responseMediaTypes:      make(map[int][]string),
+		spec:                    o,
 	}
 	if _, ok := o.endpoints[operationId]; ok {
 		e.err = errors.New("duplicate endpoint definition for operationId: " + operationId)
@@ -0,0 +1,432 @@
+package oas
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/oasm"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Parameter serialization styles.
+// See: https://swagger.io/specification/#style-values
+const (
+	StyleForm           = "form"
+	StyleSimple         = "simple"
+	StyleSpaceDelimited = "spaceDelimited"
+	StylePipeDelimited  = "pipeDelimited"
+	StyleDeepObject     = "deepObject"
+)
+
+// The styles allowed in each parameter location, the first being the default.
+var parameterStyles = map[string][]string{
+	oasm.InQuery:  {StyleForm, StyleSpaceDelimited, StylePipeDelimited, StyleDeepObject},
+	oasm.InPath:   {StyleSimple},
+	oasm.InHeader: {StyleSimple},
+	oasm.InCookie: {StyleForm},
+}
+
+type typedParameter struct {
+	kind       reflect.Kind
+	jsonSchema json.RawMessage
+	schema     map[string]interface{}
+	style      string
+	explode    bool
+	oasm.Parameter
+}
+
+func (e *endpointObject) ParameterStyle(in, name, style string, explode bool) EndpointDeclaration {
+	styles, ok := parameterStyles[in]
+	if !ok || !containsString(styles, style) {
+		e.err = fmt.Errorf("invalid style for parameter %s in %s: style should be one of %s", name, in, strings.Join(styles, ", "))
+		return e
+	}
+	setStyle := func(t *typedParameter) {
+		t.style = style
+		t.explode = explode
+	}
+
+	found := false
+	for i, p := range e.doc.Parameters {
+		if p.In == in && p.Name == name {
+			e.doc.Parameters[i].Style = style
+			e.doc.Parameters[i].Explode = explode
+			found = true
+		}
+	}
+	switch in {
+	case oasm.InQuery:
+		for i := range e.query {
+			if e.query[i].Name == name {
+				setStyle(&e.query[i])
+			}
+		}
+	case oasm.InPath:
+		for loc, p := range e.params {
+			if p.Name == name {
+				setStyle(&p)
+				e.params[loc] = p
+			}
+		}
+	case oasm.InHeader:
+		for i := range e.headers {
+			if e.headers[i].Name == name {
+				setStyle(&e.headers[i])
+			}
+		}
+	case oasm.InCookie:
+		for i := range e.cookies {
+			if e.cookies[i].Name == name {
+				setStyle(&e.cookies[i])
+			}
+		}
+	}
+	if !found {
+		e.err = fmt.Errorf("style declared before its parameter: %s %s %s", e.doc.OperationId, in, name)
+	}
+	return e
+}
+
+// Decodes a string value of a parameter into a Go type.
+type ParamDecoder func(value string) (interface{}, error)
+
+// The schema formats of the Go types of parameters which are bound to request struct fields.
+func defaultParamTypeFormats() map[reflect.Type]string {
+	return map[reflect.Type]string{
+		timeType:     "date-time",
+		durationType: "duration",
+		uuidType:     "uuid",
+	}
+}
+
+func defaultParamDecoders() map[string]ParamDecoder {
+	return map[string]ParamDecoder{
+		"date-time": func(value string) (interface{}, error) {
+			return time.Parse(time.RFC3339, value)
+		},
+		"date": func(value string) (interface{}, error) {
+			return time.Parse("2006-01-02", value)
+		},
+		"duration": func(value string) (interface{}, error) {
+			return time.ParseDuration(value)
+		},
+		"uuid": func(value string) (interface{}, error) {
+			return ParseUUID(value)
+		},
+	}
+}
+
+// A UUID, which is formatted as a string.
+type UUID [16]byte
+
+// Parse a UUID in its canonical form of hex digits: xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' {
+		return u, errors.New("invalid uuid: " + s)
+	}
+	if _, err := hex.Decode(u[:], []byte(strings.ReplaceAll(s, "-", ""))); err != nil {
+		return u, errors.New("invalid uuid: " + s)
+	}
+	return u, nil
+}
+
+func (u UUID) String() string {
+	h := hex.EncodeToString(u[:])
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+func (u UUID) MarshalText() ([]byte, error) {
+	return []byte(u.String()), nil
+}
+
+func (u *UUID) UnmarshalText(b []byte) (err error) {
+	*u, err = ParseUUID(string(b))
+	return err
+}
+
+// Read a query parameter, returning false if it was not provided.
+func (p typedParameter) fromQuery(query url.Values) (interface{}, bool, error) {
+	switch p.kind {
+	case reflect.Slice:
+		var items []string
+		if p.explode && (p.style == StyleForm || p.style == StyleSpaceDelimited || p.style == StylePipeDelimited) {
+			items = query[p.Name]
+		} else if value := query.Get(p.Name); value != "" {
+			items = strings.Split(value, map[string]string{
+				StyleForm:           ",",
+				StyleSpaceDelimited: " ",
+				StylePipeDelimited:  "|",
+			}[p.style])
+		}
+		if len(items) == 0 {
+			return nil, false, nil
+		}
+		v, err := p.convertArray(items)
+		return v, true, err
+	case reflect.Map:
+		pairs := make(map[string]string)
+		switch {
+		case p.style == StyleDeepObject:
+			for key, values := range query {
+				if strings.HasPrefix(key, p.Name+"[") && strings.HasSuffix(key, "]") {
+					pairs[key[len(p.Name)+1:len(key)-1]] = values[0]
+				}
+			}
+		case p.explode:
+			properties, _ := p.schema["properties"].(map[string]interface{})
+			for key := range properties {
+				if _, ok := query[key]; ok {
+					pairs[key] = query.Get(key)
+				}
+			}
+		default:
+			value := query.Get(p.Name)
+			if value == "" {
+				return nil, false, nil
+			}
+			var err error
+			if pairs, err = p.splitPairs(strings.Split(value, ","), false); err != nil {
+				return nil, true, err
+			}
+		}
+		if len(pairs) == 0 {
+			return nil, false, nil
+		}
+		v, err := p.convertObject(pairs)
+		return v, true, err
+	}
+	value := query.Get(p.Name)
+	if value == "" {
+		return nil, false, nil
+	}
+	v, err := p.convert(p.kind, p.schema, value)
+	return v, true, err
+}
+
+// Read a path, header, or cookie parameter, which use comma separated values.
+func (p typedParameter) fromString(value string) (interface{}, error) {
+	switch p.kind {
+	case reflect.Slice:
+		return p.convertArray(strings.Split(value, ","))
+	case reflect.Map:
+		pairs, err := p.splitPairs(strings.Split(value, ","), p.explode)
+		if err != nil {
+			return nil, err
+		}
+		return p.convertObject(pairs)
+	}
+	return p.convert(p.kind, p.schema, value)
+}
+
+// Read key value pairs from a list of items, formatted either as k=v or as alternating keys and values.
+func (p typedParameter) splitPairs(items []string, explode bool) (map[string]string, error) {
+	pairs := make(map[string]string, len(items))
+	if explode {
+		for _, item := range items {
+			i := strings.Index(item, "=")
+			if i < 0 {
+				return nil, newParameterTypeError(p.Parameter, "object", strings.Join(items, ","))
+			}
+			pairs[item[:i]] = item[i+1:]
+		}
+		return pairs, nil
+	}
+	if len(items)%2 != 0 {
+		return nil, newParameterTypeError(p.Parameter, "object", strings.Join(items, ","))
+	}
+	for i := 0; i < len(items); i += 2 {
+		pairs[items[i]] = items[i+1]
+	}
+	return pairs, nil
+}
+
+// Convert the items of an array parameter into the type of the items schema.
+func (p typedParameter) convertArray(items []string) ([]interface{}, error) {
+	schema, _ := p.schema["items"].(map[string]interface{})
+	kind := schemaKind(schema)
+	converted := make([]interface{}, 0, len(items))
+	for _, item := range items {
+		v, err := p.convert(kind, schema, item)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, v)
+	}
+	return converted, nil
+}
+
+// Convert the properties of an object parameter into the types of the property schemas.
+func (p typedParameter) convertObject(pairs map[string]string) (map[string]interface{}, error) {
+	properties, _ := p.schema["properties"].(map[string]interface{})
+	converted := make(map[string]interface{}, len(pairs))
+	for key, item := range pairs {
+		schema := p.propertySchema(properties, key)
+		v, err := p.convert(schemaKind(schema), schema, item)
+		if err != nil {
+			return nil, err
+		}
+		converted[key] = v
+	}
+	return converted, nil
+}
+
+// Get the schema of an object parameter's property.
+func (p typedParameter) propertySchema(properties map[string]interface{}, key string) map[string]interface{} {
+	if schema, ok := properties[key].(map[string]interface{}); ok {
+		return schema
+	}
+	schema, _ := p.schema["additionalProperties"].(map[string]interface{})
+	return schema
+}
+
+// Convert a single string value of the parameter into the kind.
+// Strings must be one of the values of the schema's enum, if it has one.
+func (p typedParameter) convert(kind reflect.Kind, schema map[string]interface{}, item string) (interface{}, error) {
+	switch kind {
+	case reflect.String:
+		if enum, ok := schema["enum"].([]interface{}); ok {
+			for _, v := range enum {
+				if v == item {
+					return item, nil
+				}
+			}
+			return nil, newParameterTypeError(p.Parameter, fmt.Sprint("enum ", enum), item)
+		}
+		return item, nil
+	case reflect.Int:
+		if i, err := strconv.Atoi(item); err != nil {
+			return nil, newParameterTypeError(p.Parameter, "int", item)
+		} else {
+			return i, nil
+		}
+	case reflect.Int64:
+		if i, err := strconv.ParseInt(item, 10, 64); err != nil {
+			return nil, newParameterTypeError(p.Parameter, "int64", item)
+		} else {
+			return i, nil
+		}
+	case reflect.Uint:
+		if i, err := strconv.ParseUint(item, 10, 0); err != nil {
+			return nil, newParameterTypeError(p.Parameter, "uint", item)
+		} else {
+			return uint(i), nil
+		}
+	case reflect.Float32:
+		if i, err := strconv.ParseFloat(item, 32); err != nil {
+			return nil, newParameterTypeError(p.Parameter, "float32", item)
+		} else {
+			return float32(i), nil
+		}
+	case reflect.Float64:
+		if i, err := strconv.ParseFloat(item, 64); err != nil {
+			return nil, newParameterTypeError(p.Parameter, "float", item)
+		} else {
+			return i, nil
+		}
+	case reflect.Bool:
+		if i, err := strconv.ParseBool(item); err != nil {
+			return nil, newParameterTypeError(p.Parameter, "bool", item)
+		} else {
+			return i, nil
+		}
+	default:
+		return nil, errors.New("bad reflection type for converting parameter from string")
+	}
+}
+
+// Decode the string values of a converted parameter which have a schema format with a decoder.
+func (p typedParameter) decode(decoders map[string]ParamDecoder, value interface{}) (interface{}, error) {
+	var err error
+	switch v := value.(type) {
+	case []interface{}:
+		schema, _ := p.schema["items"].(map[string]interface{})
+		for i, item := range v {
+			if v[i], err = p.decodeFormat(decoders, schema, item); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	case map[string]interface{}:
+		properties, _ := p.schema["properties"].(map[string]interface{})
+		for k, item := range v {
+			if v[k], err = p.decodeFormat(decoders, p.propertySchema(properties, k), item); err != nil {
+				return nil, err
+			}
+		}
+		return v, nil
+	}
+	return p.decodeFormat(decoders, p.schema, value)
+}
+
+func (p typedParameter) decodeFormat(decoders map[string]ParamDecoder, schema map[string]interface{}, value interface{}) (interface{}, error) {
+	format, _ := schema["format"].(string)
+	decoder, ok := decoders[format]
+	s, isString := value.(string)
+	if !ok || !isString {
+		return value, nil
+	}
+	decoded, err := decoder(s)
+	if err != nil {
+		return nil, newParameterTypeError(p.Parameter, format, s)
+	}
+	return decoded, nil
+}
+
+// Decode all parameters of the request which have a schema format with a decoder.
+func (e *endpointObject) decodeParameters(data *Data) error {
+	decode := func(param typedParameter, values MapAny) error {
+		value, ok := values[param.Name]
+		if !ok {
+			return nil
+		}
+		decoded, err := param.decode(e.spec.paramDecoders, value)
+		if err != nil {
+			return errors.WithMessage(err, "failed to decode "+param.In+" parameter "+param.Name)
+		}
+		values[param.Name] = decoded
+		return nil
+	}
+	for _, param := range e.query {
+		if err := decode(param, data.Query); err != nil {
+			return err
+		}
+	}
+	for _, param := range e.params {
+		if err := decode(param, data.Params); err != nil {
+			return err
+		}
+	}
+	for _, param := range e.headers {
+		if err := decode(param, data.Headers); err != nil {
+			return err
+		}
+	}
+	for _, param := range e.cookies {
+		if err := decode(param, data.Cookies); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get the kind that values of a (resolved) schema should be converted into.
+func schemaKind(s map[string]interface{}) reflect.Kind {
+	switch schemaType(s) {
+	case "integer":
+		if s["format"] == "int64" {
+			return reflect.Int64
+		}
+		return reflect.Int
+	case "number":
+		return reflect.Float64
+	case "boolean":
+		return reflect.Bool
+	}
+	return reflect.String
+}
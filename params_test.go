@@ -0,0 +1,252 @@
+package oas
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParameterStyles(t *testing.T) {
+	intArray := map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "integer"}, "maxItems": 3}
+	object := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"color": map[string]interface{}{"type": "string"},
+			"size":  map[string]interface{}{"type": "integer"},
+		},
+	}
+	tests := []struct {
+		name    string
+		in      string
+		style   string
+		explode bool
+		schema  map[string]interface{}
+		kind    reflect.Kind
+		target  string
+		headers map[string]string
+		status  int
+		want    string
+	}{
+		{"exploded form array", "query", StyleForm, true, intArray, reflect.Slice, "/api/items?p=1&p=2", nil, 200, `[1,2]`},
+		{"form array", "query", StyleForm, false, intArray, reflect.Slice, "/api/items?p=1,2", nil, 200, `[1,2]`},
+		{"space delimited array", "query", StyleSpaceDelimited, false, intArray, reflect.Slice, "/api/items?p=1%202", nil, 200, `[1,2]`},
+		{"pipe delimited array", "query", StylePipeDelimited, false, intArray, reflect.Slice, "/api/items?p=1|2", nil, 200, `[1,2]`},
+		{"array item of the wrong type", "query", StyleForm, true, intArray, reflect.Slice, "/api/items?p=1&p=x", nil, 400, ""},
+		{"array against its schema", "query", StyleForm, false, intArray, reflect.Slice, "/api/items?p=1,2,3,4", nil, 400, ""},
+		{"missing array", "query", StyleForm, true, intArray, reflect.Slice, "/api/items", nil, 200, `"missing"`},
+		{"deep object", "query", StyleDeepObject, true, object, reflect.Map, "/api/items?p[color]=red&p[size]=3", nil, 200, `{"color":"red","size":3}`},
+		{"exploded form object", "query", StyleForm, true, object, reflect.Map, "/api/items?color=red&size=3", nil, 200, `{"color":"red","size":3}`},
+		{"form object", "query", StyleForm, false, object, reflect.Map, "/api/items?p=color,red,size,3", nil, 200, `{"color":"red","size":3}`},
+		{"form object with a missing value", "query", StyleForm, false, object, reflect.Map, "/api/items?p=color,red,size", nil, 400, ""},
+		{"simple header array", "header", StyleSimple, false, intArray, reflect.Slice, "/api/items", map[string]string{"P": "1,2"}, 200, `[1,2]`},
+		{"simple header object", "header", StyleSimple, false, object, reflect.Map, "/api/items", map[string]string{"P": "color,red,size,3"}, 200, `{"color":"red","size":3}`},
+		{"exploded simple header object", "header", StyleSimple, true, object, reflect.Map, "/api/items", map[string]string{"P": "color=red,size=3"}, 200, `{"color":"red","size":3}`},
+		{"exploded header object without pairs", "header", StyleSimple, true, object, reflect.Map, "/api/items", map[string]string{"P": "color,red"}, 400, ""},
+		{"simple path array", "path", StyleSimple, false, intArray, reflect.Slice, "/api/items/1,2", nil, 200, `[1,2]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTestSpec(t)
+			path := "/items"
+			if tt.in == "path" {
+				path = "/items/{p}"
+			}
+			spec.NewEndpoint("getItems", "GET", path, "", "", nil).
+				Parameter(tt.in, "p", "", tt.in == "path", tt.schema, tt.kind).
+				ParameterStyle(tt.in, "p", tt.style, tt.explode).
+				Response(200, "The parameter.", nil).
+				MustDefine(func(d Data) (interface{}, error) {
+					return map[string]MapAny{"query": d.Query, "path": d.Params, "header": d.Headers}[tt.in].GetOrElse("p", "missing"), nil
+				})
+			w := serve(spec, "GET", tt.target, nil, tt.headers)
+			assertStatus(t, w, tt.status)
+			if body := strings.Join(strings.Fields(w.Body.String()), ""); tt.want != "" && body != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, body)
+			}
+		})
+	}
+}
+
+func TestParameterStyleErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		declare func(EndpointDeclaration) EndpointDeclaration
+		wantErr string
+	}{
+		{"style of another location", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.Parameter("header", "p", "", false, map[string]interface{}{"type": "string"}, reflect.String).
+				ParameterStyle("header", "p", StyleDeepObject, true)
+		}, "invalid style for parameter p in header"},
+		{"style before its parameter", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.ParameterStyle("query", "p", StyleForm, true)
+		}, "style declared before its parameter"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTestSpec(t)
+			_, err := tt.declare(spec.NewEndpoint("getItems", "GET", "/items", "", "", nil).Response(200, "ok", nil)).
+				Define(func(Data) (interface{}, error) {
+					return nil, nil
+				})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCookieParameters(t *testing.T) {
+	spec := newTestSpec(t)
+	spec.NewEndpoint("getItems", "GET", "/items", "", "", nil).
+		Parameter("cookie", "session", "", true, map[string]interface{}{"type": "string", "minLength": 3}, reflect.String).
+		Parameter("cookie", "page", "", false, map[string]interface{}{"type": "integer", "minimum": 1}, reflect.Int).
+		Parameter("cookie", "tags", "", false, map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}, reflect.Slice).
+		Response(200, "The cookies.", nil).
+		MustDefine(func(d Data) (interface{}, error) {
+			return d.Cookies, nil
+		})
+
+	tests := []struct {
+		name   string
+		cookie string
+		status int
+		want   string
+	}{
+		{"required cookie", "session=abc", 200, `{"session":"abc"}`},
+		{"converted cookie", "session=abc; page=2", 200, `{"page":2,"session":"abc"}`},
+		{"array cookie", "session=abc; tags=a,b", 200, `{"session":"abc","tags":["a","b"]}`},
+		{"missing required cookie", "page=2", 400, ""},
+		{"cookie of the wrong type", "session=abc; page=x", 400, ""},
+		{"cookie against its schema", "session=ab", 400, ""},
+		{"integer cookie against its schema", "session=abc; page=0", 400, ""},
+		{"undeclared cookie", "session=abc; other=1", 200, `{"session":"abc"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(spec, "GET", "/api/items", nil, map[string]string{"Cookie": tt.cookie})
+			assertStatus(t, w, tt.status)
+			if body := strings.Join(strings.Fields(w.Body.String()), ""); tt.want != "" && body != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, body)
+			}
+		})
+	}
+}
+
+// A type which is decoded from parameters by a decoder registered for it.
+type semver struct {
+	Major, Minor int
+}
+
+func TestParamDecoders(t *testing.T) {
+	str := func(format string) map[string]interface{} {
+		return map[string]interface{}{"type": "string", "format": format}
+	}
+	tests := []struct {
+		name   string
+		schema map[string]interface{}
+		kind   reflect.Kind
+		value  string
+		setup  func(OpenAPI)
+		status int
+		want   string
+	}{
+		{"int", map[string]interface{}{"type": "integer"}, reflect.Int, "-3", nil, 200, "int -3"},
+		{"int64", map[string]interface{}{"type": "integer", "format": "int64"}, reflect.Int64, "9007199254740993", nil, 200, "int64 9007199254740993"},
+		{"invalid int64", map[string]interface{}{"type": "integer"}, reflect.Int64, "1.5", nil, 400, ""},
+		{"uint", map[string]interface{}{"type": "integer"}, reflect.Uint, "3", nil, 200, "uint 3"},
+		{"negative uint", map[string]interface{}{"type": "integer"}, reflect.Uint, "-3", nil, 400, ""},
+		{"float32", map[string]interface{}{"type": "number"}, reflect.Float32, "1.5", nil, 200, "float32 1.5"},
+		{"bool", map[string]interface{}{"type": "boolean"}, reflect.Bool, "true", nil, 200, "bool true"},
+		{"enum", map[string]interface{}{"type": "string", "enum": []interface{}{"a", "b"}}, reflect.String, "b", nil, 200, "string b"},
+		{"value outside of the enum", map[string]interface{}{"type": "string", "enum": []interface{}{"a", "b"}}, reflect.String, "c", nil, 400, ""},
+		{"date-time", str("date-time"), reflect.String, "2020-01-02T03:04:05Z", nil, 200, "time.Time 2020-01-02 03:04:05 +0000 UTC"},
+		{"invalid date-time", str("date-time"), reflect.String, "2020-01-02", nil, 400, ""},
+		{"date", str("date"), reflect.String, "2020-01-02", nil, 200, "time.Time 2020-01-02 00:00:00 +0000 UTC"},
+		{"duration", str("duration"), reflect.String, "1m30s", nil, 200, "time.Duration 1m30s"},
+		{"invalid duration", str("duration"), reflect.String, "soon", nil, 400, ""},
+		{"uuid", str("uuid"), reflect.String, "123e4567-e89b-12d3-a456-426614174000", nil, 200, "oas.UUID 123e4567-e89b-12d3-a456-426614174000"},
+		{"uuid without dashes", str("uuid"), reflect.String, "123e4567e89b12d3a456426614174000", nil, 400, ""},
+		{"uuid with invalid digits", str("uuid"), reflect.String, "123e4567-e89b-12d3-a456-42661417400g", nil, 400, ""},
+		{"format without a decoder", str("email"), reflect.String, "a@b.c", nil, 200, "string a@b.c"},
+		{"custom decoder", str("upper"), reflect.String, "abc", func(spec OpenAPI) {
+			spec.SetParamDecoder("upper", func(value string) (interface{}, error) {
+				return strings.ToUpper(value), nil
+			})
+		}, 200, "string ABC"},
+		{"removed decoder", str("duration"), reflect.String, "1m", func(spec OpenAPI) {
+			spec.SetParamDecoder("duration", nil)
+		}, 200, "string 1m"},
+		{"items of an array", map[string]interface{}{"type": "array", "items": str("duration")}, reflect.Slice, "1s,2s", nil, 200, "[]interface {} [1s 2s]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTestSpec(t)
+			if tt.setup != nil {
+				tt.setup(spec)
+			}
+			spec.NewEndpoint("getItems", "GET", "/items", "", "", nil).
+				Parameter("query", "p", "", true, tt.schema, tt.kind).
+				ParameterStyle("query", "p", StyleForm, false).
+				Response(200, "The parameter.", nil).
+				MustDefine(func(d Data) (interface{}, error) {
+					return fmt.Sprintf("%T %v", d.Query["p"], d.Query["p"]), nil
+				})
+			w := serve(spec, "GET", "/api/items?p="+url.QueryEscape(tt.value), nil, nil)
+			assertStatus(t, w, tt.status)
+			if want, _ := json.Marshal(tt.want); tt.want != "" && strings.TrimSpace(w.Body.String()) != string(want) {
+				t.Fatalf("expected %s, got %s", want, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestParamTypeDecoder(t *testing.T) {
+	spec := newTestSpec(t)
+	spec.SetParamTypeDecoder(semver{}, "semver", func(value string) (interface{}, error) {
+		var v semver
+		if _, err := fmt.Sscanf(value, "%d.%d", &v.Major, &v.Minor); err != nil {
+			return nil, err
+		}
+		return v, nil
+	})
+	e := MustHandle(spec.NewEndpoint("getRelease", "GET", "/releases/{version}", "", "", nil),
+		func(_ Data, req struct {
+			Version  semver        `path:"version"`
+			Since    *semver       `query:"since"`
+			Timeout  time.Duration `query:"timeout"`
+			Versions []semver      `query:"versions"`
+		}) (string, error) {
+			return fmt.Sprint(req.Version, " ", req.Since, " ", req.Timeout, " ", req.Versions), nil
+		})
+
+	tests := []struct {
+		name   string
+		target string
+		status int
+		want   string
+	}{
+		{"path", "/api/releases/1.2", 200, "{1 2} <nil> 0s []"},
+		{"pointer", "/api/releases/1.2?since=1.0", 200, "{1 2} &{1 0} 0s []"},
+		{"default type", "/api/releases/1.2?timeout=5s", 200, "{1 2} <nil> 5s []"},
+		{"array", "/api/releases/1.2?versions=1.0&versions=1.1", 200, "{1 2} <nil> 0s [{1 0} {1 1}]"},
+		{"invalid", "/api/releases/one", 400, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(spec, "GET", tt.target, nil, nil)
+			assertStatus(t, w, tt.status)
+			if want, _ := json.Marshal(tt.want); tt.want != "" && strings.TrimSpace(w.Body.String()) != string(want) {
+				t.Fatalf("expected %s, got %s", want, w.Body.String())
+			}
+		})
+	}
+	for _, p := range e.Doc().Parameters {
+		if p.Name == "version" && !strings.Contains(fmt.Sprint(p.Schema), "semver") {
+			t.Fatalf("expected the parameter to have the semver format, got %v", p.Schema)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package oas
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+type pathParamsContextKey struct{}
+
+// A segment of an endpoint path.
+type PathSegment struct {
+	// The text of a static segment.
+	Static string
+	// The name of a parameter segment.
+	Param string
+	// The regular expression of a parameter segment, if it has one.
+	Pattern string
+}
+
+// Check if the segment is a parameter which matches the rest of the path (.* or .+), such as for router wildcards.
+func (s PathSegment) IsCatchAll() bool {
+	return s.Param != "" && (s.Pattern == ".*" || s.Pattern == ".+")
+}
+
+// Split an endpoint path (see: Endpoint.Settings) into its segments.
+// Parameters are written as {name} or {name:regex}.
+func PathSegments(path string) []PathSegment {
+	subMatches := pathRegex.FindAllStringSubmatch(path, -1)
+	segments := make([]PathSegment, 0, len(subMatches))
+	for _, subMatch := range subMatches {
+		if subMatch[1] == "" {
+			segments = append(segments, PathSegment{Static: strings.TrimPrefix(subMatch[0], "/")})
+		} else {
+			segments = append(segments, PathSegment{Param: subMatch[1], Pattern: subMatch[2]})
+		}
+	}
+	return segments
+}
+
+// Set the values of the path parameters of the request, as read by a router.
+// Endpoints use these values instead of matching the request path against their own path,
+// but still require values to match the regular expressions of their parameters.
+func WithPathParams(r *http.Request, params map[string]string) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), pathParamsContextKey{}, params))
+}
+
+// Get the path parameters of the request which were set by a router.
+func pathParamsFromContext(ctx context.Context) (map[string]string, bool) {
+	params, ok := ctx.Value(pathParamsContextKey{}).(map[string]string)
+	return params, ok
+}
+
+// Read the raw values of the path parameters of the request, mapped by their position in the path.
+// Returns false if the request does not match the path of the endpoint.
+func (e *endpointObject) rawPathParams(r *http.Request) (map[int]string, bool) {
+	values := make(map[int]string, len(e.parsedPath))
+	if params, ok := pathParamsFromContext(r.Context()); ok {
+		for name, loc := range e.parsedPath {
+			value, ok := params[name]
+			if !ok {
+				return nil, false
+			}
+			if pattern, ok := e.pathPatterns[name]; ok && !pattern.MatchString(value) {
+				return nil, false
+			}
+			values[loc] = value
+		}
+		return values, true
+	}
+
+	subMatches := e.regexPath.FindStringSubmatch(r.URL.Path)
+	if subMatches == nil {
+		return nil, false
+	}
+	for _, loc := range e.parsedPath {
+		values[loc] = subMatches[loc]
+	}
+	return values, true
+}
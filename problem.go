@@ -0,0 +1,225 @@
+package oas
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/oasm"
+	"net/http"
+	"reflect"
+)
+
+const (
+	MimeProblemJson = "application/problem+json"
+
+	// The name of the schema of Problem, which is registered in every spec.
+	ProblemSchemaName = "Problem"
+
+	// The type URI of problems which have no further semantics than their status code.
+	ProblemTypeBlank = "about:blank"
+)
+
+var problemType = reflect.TypeOf(Problem{})
+
+// An error which is sent to the client as an RFC 7807 problem details object, using the media type
+// application/problem+json.
+// Handlers may return a *Problem as their error to respond with it.
+//
+// Extensions are additional members of the problem object, and may not replace the standard members.
+type Problem struct {
+	Type       string
+	Title      string
+	Status     int
+	Detail     string
+	Instance   string
+	Extensions map[string]interface{}
+}
+
+// Create a problem with the blank type, titled with the text of the status code.
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   ProblemTypeBlank,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+// Set an extension member of the problem.
+func (p *Problem) With(name string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = make(map[string]interface{})
+	}
+	p.Extensions[name] = value
+	return p
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return fmt.Sprintf("%d %s", p.Status, p.Title)
+	}
+	return fmt.Sprintf("%d %s: %s", p.Status, p.Title, p.Detail)
+}
+
+func (p Problem) MarshalJSON() ([]byte, error) {
+	object := make(map[string]interface{}, len(p.Extensions)+5)
+	for k, v := range p.Extensions {
+		object[k] = v
+	}
+	object["type"] = p.Type
+	if p.Type == "" {
+		object["type"] = ProblemTypeBlank
+	}
+	object["title"] = p.Title
+	object["status"] = p.Status
+	if p.Detail != "" {
+		object["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		object["instance"] = p.Instance
+	}
+	return json.Marshal(object)
+}
+
+func (p *Problem) UnmarshalJSON(b []byte) error {
+	var object map[string]interface{}
+	if err := json.Unmarshal(b, &object); err != nil {
+		return err
+	}
+	*p = Problem{}
+	p.Type, _ = object["type"].(string)
+	p.Title, _ = object["title"].(string)
+	p.Detail, _ = object["detail"].(string)
+	p.Instance, _ = object["instance"].(string)
+	if status, ok := object["status"].(float64); ok {
+		p.Status = int(status)
+	}
+	for _, k := range []string{"type", "title", "status", "detail", "instance"} {
+		delete(object, k)
+	}
+	if len(object) > 0 {
+		p.Extensions = object
+	}
+	return nil
+}
+
+// The JSON Schema of Problem.
+func problemSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type":     "object",
+		"required": []string{"type", "title", "status"},
+		"properties": map[string]interface{}{
+			"type":     map[string]interface{}{"type": "string", "format": "uri-reference"},
+			"title":    map[string]interface{}{"type": "string"},
+			"status":   map[string]interface{}{"type": "integer", "minimum": 100, "maximum": 599},
+			"detail":   map[string]interface{}{"type": "string"},
+			"instance": map[string]interface{}{"type": "string", "format": "uri-reference"},
+		},
+		"additionalProperties": true,
+	}
+}
+
+// Register the schema of Problem, so that it may be referenced by error responses.
+func (o *openAPI) registerProblemSchema() error {
+	if _, ok := o.validatorBuilder.GetSchemas()[ProblemSchemaName]; ok {
+		return fmt.Errorf("schema name %s is reserved for problem details", ProblemSchemaName)
+	}
+	b, err := json.Marshal(problemSchema())
+	if err != nil {
+		return errors.WithMessage(err, "failed to marshal problem schema")
+	}
+	if err = o.validatorBuilder.AddSchema(ProblemSchemaName, b); err != nil {
+		return errors.WithMessage(err, "failed to add problem schema")
+	}
+	if o.doc.Components.Schemas[ProblemSchemaName], err = docSchema(b); err != nil {
+		return err
+	}
+	o.schemaTypes[problemType] = ProblemSchemaName
+	return nil
+}
+
+// Convert an error into the problem which is sent to the client.
+// Returns false if the error was not expected, in which case the problem hides the details of the error.
+func errorToProblem(err error) (*Problem, bool) {
+	var p *Problem
+	if errors.As(err, &p) {
+		if p.Status == 0 {
+			problem := *p
+			problem.Status = 500
+			return &problem, true
+		}
+		return p, true
+	}
+	switch cause := errors.Cause(err).(type) {
+	case jsonValidationError:
+		return validationProblem(400, cause.Type, "The request is invalid.", cause.Errors), true
+	case malformedJSONError:
+		return validationProblem(400, "MalformedRequestError", string(cause), nil), true
+	case unsupportedMediaTypeError:
+		return validationProblem(415, cause.Type, "The request content type is not supported.", cause.Errors), true
+	}
+	return NewProblem(500, ""), false
+}
+
+// Create a problem for a built-in error type, listing the individual errors as the errors extension member.
+func validationProblem(status int, errorType, detail string, errorList []string) *Problem {
+	p := NewProblem(status, detail)
+	p.Type = "urn:oas:problem:" + errorType
+	if errorList != nil {
+		p.With("errors", errorList)
+	}
+	return p
+}
+
+// Check if a response body is a problem, which is always sent as problem+json.
+func isProblem(body interface{}) bool {
+	switch body.(type) {
+	case Problem, *Problem:
+		return true
+	}
+	return false
+}
+
+// Document problems as the content of error responses (including ranges and a declared default response) which have none,
+// and add the responses for problems which may be sent by the endpoint itself.
+func (e *endpointObject) documentProblems() error {
+	schema, err := docSchema([]byte(`{"$ref":"{` + ProblemSchemaName + `}"}`))
+	if err != nil {
+		return err
+	}
+	content := oasm.MediaTypesMap{
+		MimeProblemJson: {
+			Schema: schema,
+		},
+	}
+
+	if len(e.query)+len(e.params)+len(e.headers)+len(e.cookies)+len(e.bodies) > 0 {
+		if _, ok := e.doc.Responses.Codes[400]; !ok {
+			e.doc.Responses.Codes[400] = oasm.Response{Description: "The request is invalid."}
+		}
+	}
+	if len(e.bodies) > 0 {
+		if _, ok := e.doc.Responses.Codes[415]; !ok {
+			e.doc.Responses.Codes[415] = oasm.Response{Description: "The request content type is not supported."}
+		}
+		if _, ok := e.doc.Responses.Codes[413]; !ok && e.spec.maxRequestBodySize > 0 {
+			e.doc.Responses.Codes[413] = oasm.Response{Description: "The request body is too large."}
+		}
+	}
+	for code, r := range e.doc.Responses.Codes {
+		if code >= 400 && len(r.Content) == 0 {
+			r.Content = content
+			e.doc.Responses.Codes[code] = r
+		}
+	}
+	for class, r := range e.responseRanges {
+		if class >= 4 && len(r.Content) == 0 {
+			r.Content = content
+			e.responseRanges[class] = r
+		}
+	}
+	if e.doc.Responses.Default != nil && len(e.doc.Responses.Default.Content) == 0 {
+		e.doc.Responses.Default.Content = content
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package oas
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestProblemResponses(t *testing.T) {
+	spec := newTestSpec(t)
+	var returned error
+	spec.NewEndpoint("createItem", "POST", "/items", "", "", nil).
+		Parameter("query", "limit", "", false, map[string]interface{}{"type": "integer"}, reflect.Int).
+		RequestBody("The item.", true, map[string]interface{}{
+			"type": "object", "required": []string{"name"}, "properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+		}, nil).
+		Response(200, "The item.", nil).
+		Response(409, "The item exists.", nil).
+		MustDefine(func(Data) (interface{}, error) {
+			return "created", returned
+		})
+
+	tests := []struct {
+		name     string
+		target   string
+		body     string
+		returned error
+		status   int
+		want     map[string]interface{}
+	}{
+		{"success", "/api/items", `{"name":"a"}`, nil, 200, nil},
+		{"invalid body", "/api/items", `{}`, nil, 400,
+			map[string]interface{}{"type": "urn:oas:problem:JSONValidationError", "title": "Bad Request", "status": 400.0}},
+		{"malformed body", "/api/items", `{`, nil, 400,
+			map[string]interface{}{"type": "urn:oas:problem:MalformedRequestError", "status": 400.0}},
+		{"parameter of the wrong type", "/api/items?limit=x", `{"name":"a"}`, nil, 400, map[string]interface{}{"status": 400.0}},
+		{"returned problem", "/api/items", `{"name":"a"}`, NewProblem(409, "It exists.").With("id", "a"), 409,
+			map[string]interface{}{"type": "about:blank", "title": "Conflict", "status": 409.0, "detail": "It exists.", "id": "a"}},
+		{"wrapped problem", "/api/items", `{"name":"a"}`, wrap(NewProblem(409, "")), 409, map[string]interface{}{"status": 409.0}},
+		{"problem without a status", "/api/items", `{"name":"a"}`, &Problem{Title: "Oops"}, 500,
+			map[string]interface{}{"type": "about:blank", "title": "Oops", "status": 500.0}},
+		{"unexpected error", "/api/items", `{"name":"a"}`, errors.New("secret details"), 500,
+			map[string]interface{}{"type": "about:blank", "title": "Internal Server Error", "status": 500.0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			returned = tt.returned
+			w := serve(spec, "POST", tt.target, strings.NewReader(tt.body), map[string]string{"Content-Type": "application/json"})
+			assertStatus(t, w, tt.status)
+			if tt.want == nil {
+				return
+			}
+			if contentType := w.Header().Get("Content-Type"); contentType != MimeProblemJson {
+				t.Fatalf("expected a problem, got %s", contentType)
+			}
+			var problem map[string]interface{}
+			if err := json.Unmarshal(w.Body.Bytes(), &problem); err != nil {
+				t.Fatal(err)
+			}
+			for k, v := range tt.want {
+				if !reflect.DeepEqual(problem[k], v) {
+					t.Fatalf("expected %s to be %v, got %s", k, v, w.Body.String())
+				}
+			}
+			if strings.Contains(w.Body.String(), "secret") {
+				t.Fatalf("expected the error to be hidden, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+// Wrap an error with a message, as handlers do.
+func wrap(err error) error {
+	return &wrappedError{err}
+}
+
+type wrappedError struct {
+	err error
+}
+
+func (w *wrappedError) Error() string { return "wrapped: " + w.err.Error() }
+func (w *wrappedError) Unwrap() error { return w.err }
+
+func TestProblemMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		problem Problem
+		want    string
+	}{
+		{"blank", *NewProblem(404, ""), `{"status":404,"title":"Not Found","type":"about:blank"}`},
+		{"empty type", Problem{Title: "Oops", Status: 500}, `{"status":500,"title":"Oops","type":"about:blank"}`},
+		{"all members", Problem{Type: "urn:x", Title: "X", Status: 400, Detail: "d", Instance: "/i"},
+			`{"detail":"d","instance":"/i","status":400,"title":"X","type":"urn:x"}`},
+		{"extensions do not replace members", Problem{Title: "X", Status: 400, Extensions: map[string]interface{}{"status": 1, "id": 2}},
+			`{"id":2,"status":400,"title":"X","type":"about:blank"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := json.Marshal(tt.problem)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, b)
+			}
+			var decoded Problem
+			if err = json.Unmarshal(b, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if again, _ := json.Marshal(decoded); string(again) != tt.want {
+				t.Fatalf("expected the problem to round trip, got %s", again)
+			}
+		})
+	}
+}
+
+func TestDocumentProblems(t *testing.T) {
+	tests := []struct {
+		name      string
+		declare   func(EndpointDeclaration) EndpointDeclaration
+		responses []string
+		problems  []string
+	}{
+		{"no parameters", func(e EndpointDeclaration) EndpointDeclaration { return e }, []string{"200"}, nil},
+		{"parameter", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.Parameter("query", "q", "", false, map[string]interface{}{"type": "string"}, reflect.String)
+		}, []string{"200", "400"}, []string{"400"}},
+		{"request body", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.RequestBody("The item.", true, map[string]interface{}{"type": "object"}, nil)
+		}, []string{"200", "400", "415"}, []string{"400", "415"}},
+		{"declared error response", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.Response(404, "Not found.", nil)
+		}, []string{"200", "404"}, []string{"404"}},
+		{"declared error response with content", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.Response(404, "Not found.", map[string]interface{}{"type": "string"})
+		}, []string{"200", "404"}, nil},
+		{"declared 400 is kept", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.Parameter("query", "q", "", false, map[string]interface{}{"type": "string"}, reflect.String).
+				Response(400, "Bad query.", map[string]interface{}{"type": "string"})
+		}, []string{"200", "400"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTestSpec(t)
+			e := tt.declare(spec.NewEndpoint("getItem", "POST", "/items", "", "", nil).Response(200, "The item.", nil)).
+				MustDefine(func(Data) (interface{}, error) {
+					return nil, nil
+				})
+			var responses, problems []string
+			for code, r := range e.Doc().Responses.Codes {
+				responses = append(responses, strconv.Itoa(code))
+				if _, ok := r.Content[MimeProblemJson]; ok {
+					problems = append(problems, strconv.Itoa(code))
+				}
+			}
+			sort.Strings(responses)
+			sort.Strings(problems)
+			if !reflect.DeepEqual(responses, tt.responses) || !reflect.DeepEqual(problems, tt.problems) {
+				t.Fatalf("expected %v with problems %v, got %v with problems %v", tt.responses, tt.problems, responses, problems)
+			}
+			if e.Doc().Responses.Default != nil {
+				t.Fatal("expected no default response")
+			}
+		})
+	}
+}
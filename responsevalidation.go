@@ -0,0 +1,121 @@
+package oas
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"math/rand"
+	"strings"
+)
+
+// Determines what happens when a response body does not match the schema of its response.
+type ResponseValidationMode int
+
+const (
+	// Response bodies are not validated.
+	ResponseValidationOff ResponseValidationMode = iota
+	// Response bodies are validated, and failures are reported after the response is sent. (Default)
+	ResponseValidationLog
+	// Response bodies are validated before they are sent, and are replaced by a 500 problem if they fail.
+	// Failures are also reported.
+	ResponseValidationFail
+)
+
+// A response body which did not match the schema of its response.
+type ResponseValidationFailure struct {
+	OperationId string
+	Status      int
+	// The name of the schema that the body was validated against.
+	Schema string
+	// The JSON encoding of the body, which is nil if the body could not be encoded.
+	Body   json.RawMessage
+	Errors []string
+}
+
+func (f ResponseValidationFailure) Error() string {
+	return fmt.Sprintf("response body failed validation for %s status %v:\n\t%s",
+		f.OperationId, f.Status, strings.Join(f.Errors, "\n\t"))
+}
+
+// Receives response validation failures, in addition to them being reported as errors.
+type ResponseValidationHandler func(Data, ResponseValidationFailure)
+
+// The response validation mode, and the fraction of responses which are validated.
+type responseValidation struct {
+	mode       ResponseValidationMode
+	sampleRate float64
+}
+
+func newResponseValidation(mode ResponseValidationMode, sampleRate float64) responseValidation {
+	if sampleRate < 0 {
+		sampleRate = 0
+	} else if sampleRate > 1 {
+		sampleRate = 1
+	}
+	return responseValidation{mode: mode, sampleRate: sampleRate}
+}
+
+// Check if a response should be validated.
+func (v responseValidation) sample() bool {
+	return v.mode != ResponseValidationOff && (v.sampleRate >= 1 || rand.Float64() < v.sampleRate)
+}
+
+func (o *openAPI) SetResponseValidation(mode ResponseValidationMode, sampleRate float64) {
+	o.responseValidation = newResponseValidation(mode, sampleRate)
+}
+
+func (o *openAPI) SetResponseValidationHandler(handler ResponseValidationHandler) {
+	o.responseValidationHandler = handler
+}
+
+func (e *endpointObject) ResponseValidation(mode ResponseValidationMode, sampleRate float64) EndpointDeclaration {
+	v := newResponseValidation(mode, sampleRate)
+	e.responseValidation = &v
+	return e
+}
+
+// Get the response validation settings of the endpoint, falling back to those of the spec.
+func (e *endpointObject) responseValidationSettings() responseValidation {
+	if e.responseValidation != nil {
+		return *e.responseValidation
+	}
+	return e.spec.responseValidation
+}
+
+// Validate a response body against the schema of its response, if it has one.
+// Returns a failure if the body is invalid.
+func (e *endpointObject) validateResponse(res Response) *ResponseValidationFailure {
+	schema, ok := e.responseSchemaRef(res.Status)
+	if !ok {
+		return nil
+	}
+	failure := &ResponseValidationFailure{
+		OperationId: e.doc.OperationId,
+		Status:      res.Status,
+		Schema:      schema,
+	}
+	bodyBytes, err := json.Marshal(res.Body)
+	if err != nil {
+		failure.Errors = []string{errors.WithMessage(err, "failed to marshal response body").Error()}
+		return failure
+	}
+	failure.Body = bodyBytes
+	result, err := e.spec.validator.Validate(schema, bodyBytes)
+	if err != nil {
+		failure.Errors = []string{errors.WithMessage(err, "response body contains malformed json").Error()}
+		return failure
+	}
+	if !result.Valid() {
+		failure.Errors = newJSONValidationError(result).Errors
+		return failure
+	}
+	return nil
+}
+
+// Report a response validation failure as an error, and to the response validation handler.
+func (e *endpointObject) reportResponseValidationFailure(data Data, failure ResponseValidationFailure) {
+	e.printError(failure)
+	if e.spec.responseValidationHandler != nil {
+		e.spec.responseValidationHandler(data, failure)
+	}
+}
@@ -0,0 +1,129 @@
+package oas
+
+import (
+	"strings"
+	"testing"
+)
+
+// Create a spec with an endpoint whose response must be an object with a name.
+func responseValidationSpec(t *testing.T, failures *[]ResponseValidationFailure) (OpenAPI, EndpointDeclaration) {
+	t.Helper()
+	spec := newTestSpec(t)
+	spec.SetResponseValidationHandler(func(_ Data, f ResponseValidationFailure) {
+		*failures = append(*failures, f)
+	})
+	e := spec.NewEndpoint("getItem", "GET", "/item", "", "", nil).
+		Response(200, "The item.", map[string]interface{}{
+			"type":       "object",
+			"required":   []string{"name"},
+			"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string"}},
+		})
+	return spec, e
+}
+
+func TestResponseValidationModes(t *testing.T) {
+	valid := map[string]interface{}{"name": "a"}
+	invalid := map[string]interface{}{"name": 1}
+	tests := []struct {
+		name     string
+		spec     ResponseValidationMode
+		endpoint *ResponseValidationMode
+		body     interface{}
+		status   int
+		failures int
+	}{
+		{"off", ResponseValidationOff, nil, invalid, 200, 0},
+		{"log valid", ResponseValidationLog, nil, valid, 200, 0},
+		{"log invalid", ResponseValidationLog, nil, invalid, 200, 1},
+		{"fail valid", ResponseValidationFail, nil, valid, 200, 0},
+		{"fail invalid", ResponseValidationFail, nil, invalid, 500, 1},
+		{"endpoint fails", ResponseValidationOff, modePtr(ResponseValidationFail), invalid, 500, 1},
+		{"endpoint off", ResponseValidationFail, modePtr(ResponseValidationOff), invalid, 200, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failures []ResponseValidationFailure
+			spec, e := responseValidationSpec(t, &failures)
+			spec.SetResponseValidation(tt.spec, 1)
+			if tt.endpoint != nil {
+				e.ResponseValidation(*tt.endpoint, 1)
+			}
+			e.MustDefine(func(Data) (interface{}, error) { return tt.body, nil })
+
+			w := serve(spec, "GET", "/api/item", nil, nil)
+			assertStatus(t, w, tt.status)
+			if len(failures) != tt.failures {
+				t.Fatalf("expected %v failures, got %v: %+v", tt.failures, len(failures), failures)
+			}
+			if tt.status == 500 && !strings.Contains(w.Body.String(), "The response failed validation.") {
+				t.Fatalf("expected the response to be replaced by a problem: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+func modePtr(mode ResponseValidationMode) *ResponseValidationMode {
+	return &mode
+}
+
+func TestResponseValidationFailure(t *testing.T) {
+	var failures []ResponseValidationFailure
+	spec, e := responseValidationSpec(t, &failures)
+	e.MustDefine(func(Data) (interface{}, error) { return map[string]interface{}{"name": 1}, nil })
+
+	assertStatus(t, serve(spec, "GET", "/api/item", nil, nil), 200)
+	if len(failures) != 1 {
+		t.Fatalf("expected 1 failure, got %v", len(failures))
+	}
+	f := failures[0]
+	if f.OperationId != "getItem" || f.Status != 200 || f.Schema == "" || string(f.Body) != `{"name":1}` || len(f.Errors) == 0 {
+		t.Fatalf("unexpected failure: %+v", f)
+	}
+	if !strings.Contains(f.Error(), "getItem status 200") {
+		t.Fatalf("expected the error to name the operation and status: %v", f.Error())
+	}
+}
+
+func TestResponseValidationSampling(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       ResponseValidationMode
+		sampleRate float64
+		want       int
+	}{
+		{"all", ResponseValidationLog, 1, 20},
+		{"above one", ResponseValidationLog, 5, 20},
+		{"none", ResponseValidationLog, 0, 0},
+		{"below zero", ResponseValidationFail, -1, 0},
+		{"off", ResponseValidationOff, 1, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newResponseValidation(tt.mode, tt.sampleRate)
+			if v.sampleRate < 0 || v.sampleRate > 1 {
+				t.Fatalf("expected the sample rate to be clamped, got %v", v.sampleRate)
+			}
+			sampled := 0
+			for i := 0; i < 20; i++ {
+				if v.sample() {
+					sampled++
+				}
+			}
+			if sampled != tt.want {
+				t.Fatalf("expected %v sampled responses, got %v", tt.want, sampled)
+			}
+		})
+	}
+
+	// A fractional rate validates some responses, but not all of them.
+	v := newResponseValidation(ResponseValidationLog, 0.5)
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if v.sample() {
+			sampled++
+		}
+	}
+	if sampled == 0 || sampled == 1000 {
+		t.Fatalf("expected some responses to be sampled, got %v of 1000", sampled)
+	}
+}
@@ -0,0 +1,281 @@
+package oas
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+)
+
+// Check that every parameter in the path of the endpoint is documented.
+func (e *endpointObject) checkPathParams() error {
+	for _, segment := range PathSegments(e.path) {
+		if segment.Param == "" {
+			continue
+		}
+		if _, ok := e.params[e.parsedPath[segment.Param]]; !ok {
+			return errors.New(fmt.Sprintf("path parameter %s is in the path of %s, but is not documented: %s",
+				segment.Param, e.doc.OperationId, e.path))
+		}
+	}
+	return nil
+}
+
+// Check that the route of the endpoint does not conflict with the routes of the spec which have the same method.
+// Routes conflict when they have the same path template, when they may match the same request path without
+// either one taking precedence, or when one takes precedence over every request path that the other matches.
+// When the endpoints are mounted by a router which matches parameters without their regular expressions,
+// routes also conflict when they differ only by the regular expressions of their parameters. See: PatternlessRouter
+func (o *openAPI) checkRoute(e *endpointObject) error {
+	r := newRoute(e, e.regexPath)
+	for _, other := range o.routes {
+		if other.method != r.method {
+			continue
+		}
+		describe := func(problem string) error {
+			return errors.New(fmt.Sprintf("route %s %s of %s %s the route %s %s of %s",
+				r.method, e.path, e.doc.OperationId, problem, other.method, other.endpoint.path, other.endpoint.doc.OperationId))
+		}
+		switch {
+		case r.template() == other.template():
+			return describe("duplicates")
+		case o.patternlessRouter && r.routerTemplate() == other.routerTemplate():
+			return describe("differs only by the regular expressions of its parameters from")
+		case r.ambiguousWith(other):
+			return describe("is ambiguous with")
+		case other.precedes(r) && other.covers(r):
+			return describe("is shadowed by")
+		case r.precedes(other) && r.covers(other):
+			return describe("shadows")
+		}
+	}
+	return nil
+}
+
+// Declare that the endpoint is mounted by a router which matches path parameters without their regular expressions,
+// and which matches a final catch-all parameter (such as {name:.*}) as a wildcard.
+// RouteCreators for such routers call this when they mount each endpoint, so that endpoints which are defined
+// afterwards are rejected if their paths differ from that of another endpoint only by the regular expressions.
+func PatternlessRouter(endpoint Endpoint) {
+	if e, ok := endpoint.(*endpointObject); ok {
+		e.spec.patternlessRouter = true
+	}
+}
+
+// Get the path of the route with the names of its parameters removed.
+func (r route) template() string {
+	return r.pathTemplate(true)
+}
+
+// Get the path of the route as it is matched by a router without regular expressions,
+// where parameters match any segment, and a final catch-all parameter matches the rest of the path.
+func (r route) routerTemplate() string {
+	return r.pathTemplate(false)
+}
+
+func (r route) pathTemplate(patterns bool) string {
+	var b strings.Builder
+	for i, segment := range r.segments {
+		b.WriteByte('/')
+		switch {
+		case segment.Param == "":
+			b.WriteString(segment.Static)
+		case patterns && segment.Pattern != "":
+			b.WriteString("{:" + segment.Pattern + "}")
+		case i == len(r.segments)-1 && segment.IsCatchAll():
+			b.WriteString("{...}")
+		default:
+			b.WriteString("{}")
+		}
+	}
+	return b.String()
+}
+
+// Check if the routes may match the same request path, while neither one takes precedence by its segments.
+func (r route) ambiguousWith(other route) bool {
+	if len(r.segments) != len(other.segments) {
+		return false
+	}
+	for i, segment := range r.segments {
+		otherSegment := other.segments[i]
+		if r.ranks[i] != other.ranks[i] {
+			return false
+		}
+		switch r.ranks[i] {
+		case rankStatic:
+			if segment.Static != otherSegment.Static {
+				return false
+			}
+		case rankPatternParam:
+			if !patternsOverlap(segment.Pattern, otherSegment.Pattern) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Check if the route matches every request path which the other route matches.
+func (r route) covers(other route) bool {
+	for i, segment := range r.segments {
+		if i == len(r.segments)-1 && segment.IsCatchAll() {
+			return len(other.segments) > i
+		}
+		if i >= len(other.segments) {
+			return false
+		}
+		otherSegment := other.segments[i]
+		switch {
+		case otherSegment.IsCatchAll() && !segment.IsCatchAll():
+			return false
+		case segment.Param == "":
+			if otherSegment.Param != "" || segment.Static != otherSegment.Static {
+				return false
+			}
+		case segment.Pattern == "" || segment.IsCatchAll():
+		case otherSegment.Param == "":
+			if matched, err := regexp.MatchString("^(?:"+segment.Pattern+")$", otherSegment.Static); err != nil || !matched {
+				return false
+			}
+		case segment.Pattern != otherSegment.Pattern:
+			return false
+		}
+	}
+	return len(r.segments) == len(other.segments)
+}
+
+// Check if two regular expressions may match the same path segment.
+// The programs of the regular expressions are run together over every rune except '/', in the same way as the
+// product of two automata, until both of them match. Empty-width assertions (such as \b) are assumed to hold,
+// so an overlap may be reported where there is none, but never the reverse.
+func patternsOverlap(a, b string) bool {
+	if a == b {
+		return true
+	}
+	progA, errA := compilePattern(a)
+	progB, errB := compilePattern(b)
+	if errA != nil || errB != nil {
+		return true
+	}
+
+	type state struct{ a, b uint32 }
+	startA, matchA := progClosure(progA, uint32(progA.Start))
+	startB, matchB := progClosure(progB, uint32(progB.Start))
+	if matchA && matchB {
+		return true
+	}
+	var queue []state
+	seen := make(map[state]bool)
+	push := func(pcsA, pcsB []uint32) {
+		for _, pcA := range pcsA {
+			for _, pcB := range pcsB {
+				if s := (state{pcA, pcB}); !seen[s] {
+					seen[s] = true
+					queue = append(queue, s)
+				}
+			}
+		}
+	}
+	push(startA, startB)
+	for len(queue) > 0 {
+		s := queue[0]
+		queue = queue[1:]
+		instA, instB := &progA.Inst[s.a], &progB.Inst[s.b]
+		if !runeRangesIntersect(instRunes(instA), instRunes(instB)) {
+			continue
+		}
+		nextA, matchA := progClosure(progA, instA.Out)
+		nextB, matchB := progClosure(progB, instB.Out)
+		if matchA && matchB {
+			return true
+		}
+		push(nextA, nextB)
+	}
+	return false
+}
+
+func compilePattern(pattern string) (*syntax.Prog, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	return syntax.Compile(re.Simplify())
+}
+
+// Follow the instructions of the program from pc which do not consume a rune, returning those which do,
+// and whether the program may match without consuming another rune.
+func progClosure(prog *syntax.Prog, pc uint32) ([]uint32, bool) {
+	var (
+		pcs     []uint32
+		matched bool
+		seen    = make(map[uint32]bool)
+		visit   func(pc uint32)
+	)
+	visit = func(pc uint32) {
+		if seen[pc] {
+			return
+		}
+		seen[pc] = true
+		inst := &prog.Inst[pc]
+		switch inst.Op {
+		case syntax.InstMatch:
+			matched = true
+		case syntax.InstAlt, syntax.InstAltMatch:
+			visit(inst.Out)
+			visit(inst.Arg)
+		case syntax.InstCapture, syntax.InstNop, syntax.InstEmptyWidth:
+			visit(inst.Out)
+		case syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			pcs = append(pcs, pc)
+		}
+	}
+	visit(pc)
+	return pcs, matched
+}
+
+// Get the ranges of the runes that the instruction consumes, as pairs of the low and high rune.
+func instRunes(inst *syntax.Inst) []rune {
+	switch inst.Op {
+	case syntax.InstRune1:
+		return []rune{inst.Rune[0], inst.Rune[0]}
+	case syntax.InstRuneAny:
+		return []rune{0, unicode.MaxRune}
+	case syntax.InstRuneAnyNotNL:
+		return []rune{0, '\n' - 1, '\n' + 1, unicode.MaxRune}
+	}
+	if len(inst.Rune) == 1 && syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+		var ranges []rune
+		r := inst.Rune[0]
+		for f := r; ; {
+			ranges = append(ranges, f, f)
+			if f = unicode.SimpleFold(f); f == r {
+				return ranges
+			}
+		}
+	}
+	if len(inst.Rune) == 1 {
+		return []rune{inst.Rune[0], inst.Rune[0]}
+	}
+	return inst.Rune
+}
+
+// Check if the rune ranges have a rune in common, other than '/', which never occurs within a path segment.
+func runeRangesIntersect(a, b []rune) bool {
+	for i := 0; i+1 < len(a); i += 2 {
+		for j := 0; j+1 < len(b); j += 2 {
+			lo, hi := a[i], a[i+1]
+			if b[j] > lo {
+				lo = b[j]
+			}
+			if b[j+1] < hi {
+				hi = b[j+1]
+			}
+			if lo <= hi && !(lo == '/' && hi == '/') {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package oas
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Define an endpoint which documents each parameter of its path.
+func defineRoute(spec OpenAPI, operationId, method, path string) error {
+	e := spec.NewEndpoint(operationId, method, path, "", "", nil).Response(200, "The operation.", nil)
+	for _, segment := range PathSegments(path) {
+		if segment.Param != "" {
+			e = e.Parameter("path", segment.Param, "", true, map[string]interface{}{"type": "string"}, reflect.String)
+		}
+	}
+	_, err := e.Define(func(Data) (interface{}, error) {
+		return operationId, nil
+	})
+	return err
+}
+
+func TestCheckRoute(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []string
+		route    string
+		// Whether the endpoints are mounted by a router which matches parameters without their regular expressions.
+		router  bool
+		wantErr string
+	}{
+		{"different static paths", []string{"GET /pets"}, "GET /owners", false, ""},
+		{"different methods", []string{"GET /pets/{id}"}, "DELETE /pets/{id}", false, ""},
+		{"same path", []string{"GET /pets"}, "GET /pets", false, "duplicates"},
+		{"parameters with different names", []string{"GET /pets/{id}"}, "GET /pets/{name}", false, "duplicates"},
+		{"patterns with different names", []string{"GET /pets/{id:[0-9]+}"}, "GET /pets/{n:[0-9]+}", false, "duplicates"},
+		{"static segment before a parameter", []string{"GET /pets/{id}"}, "GET /pets/mine", false, ""},
+		{"parameter after a static segment", []string{"GET /pets/mine"}, "GET /pets/{id}", false, ""},
+		{"pattern before a parameter", []string{"GET /pets/{id}"}, "GET /pets/{id:[0-9]+}", false, ""},
+		{"disjoint patterns", []string{"GET /pets/{id:[0-9]+}"}, "GET /pets/{name:[a-z]+}", false, ""},
+		{"overlapping patterns", []string{"GET /pets/{id:[0-9]+}"}, "GET /pets/{code:[0-9a-f]+}", false, "is ambiguous with"},
+		{"case insensitive patterns", []string{"GET /pets/{name:[a-z]+}"}, "GET /pets/{code:(?i)A[a-z]+}", false, "is ambiguous with"},
+		{"case insensitive patterns which differ later", []string{"GET /pets/{name:[a-z]+}"}, "GET /pets/{code:(?i)A[0-9]+}", false, ""},
+		{"optional patterns", []string{"GET /pets/{id:[0-9]+}"}, "GET /pets/{name:x?[a-z]*}", false, ""},
+		{"optional patterns which overlap", []string{"GET /pets/{id:[0-9]+}"}, "GET /pets/{name:x?[0-9]*}", false, "is ambiguous with"},
+		{"patterns which start differently", []string{"GET /pets/{id:a[0-9]+}"}, "GET /pets/{name:b[0-9]+}", false, ""},
+		{"patterns which start the same", []string{"GET /files/{id:[0-9]+}"}, "GET /files/{date:[0-9][0-9][0-9][0-9]-[0-9][0-9]}", false, ""},
+		{"patterns with a common value", []string{"GET /files/{id:[0-9]+}"}, "GET /files/{year:[0-9][0-9][0-9][0-9]}", false, "is ambiguous with"},
+		{"patterns which match only across segments", []string{"GET /files/{a:x/y}"}, "GET /files/{b:x.y}", false, ""},
+		{"parameters in different segments", []string{"GET /pets/{id}/toys"}, "GET /pets/mine/{toy}", false, ""},
+		{"different numbers of segments", []string{"GET /pets/{id}"}, "GET /pets/{id}/toys", false, ""},
+		{"catch-all before a parameter", []string{"GET /files/{name}"}, "GET /files/{path:.*}", false, "shadows"},
+		{"parameter after a catch-all", []string{"GET /files/{path:.+}"}, "GET /files/{dir}/{name}", false, "is shadowed by"},
+		{"static path after a catch-all", []string{"GET /files/{path:.*}"}, "GET /files/readme", false, ""},
+		{"pattern which matches a static path after it", []string{"GET /pets/{id:[0-9]+}"}, "GET /pets/123", false, ""},
+		{"disjoint patterns with a router", []string{"GET /pets/{id:[0-9]+}"}, "GET /pets/{name:[a-z]+}", true, "differs only by the regular expressions"},
+		{"pattern and parameter with a router", []string{"GET /pets/{id}"}, "GET /pets/{id:[0-9]+}", true, "differs only by the regular expressions"},
+		{"catch-all patterns with a router", []string{"GET /files/{path:.+}/raw"}, "GET /files/{path:.*}", true, ""},
+		{"different catch-all patterns with a router", []string{"POST /files/{path:.+}"}, "POST /files/{name:.*}", true, "differs only by the regular expressions"},
+		{"different methods with a router", []string{"GET /pets/{id:[0-9]+}"}, "PUT /pets/{name:[a-z]+}", true, ""},
+		{"static segment before a parameter with a router", []string{"GET /pets/{id}"}, "GET /pets/mine", true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTestSpec(t)
+			if tt.router {
+				var err error
+				spec, _, err = NewOpenAPI("Test", "", "http://localhost/api", "1.0.0", t.TempDir(), nil,
+					func(e Endpoint, _ http.Handler) { PatternlessRouter(e) })
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			for i, route := range append(tt.existing, tt.route) {
+				method, path, _ := strings.Cut(route, " ")
+				err := defineRoute(spec, "operation"+string(rune('A'+i)), method, path)
+				if i < len(tt.existing) {
+					if err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				if tt.wantErr == "" && err != nil {
+					t.Fatalf("expected the route to be allowed, got %v", err)
+				}
+				if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+					t.Fatalf("expected an error containing %q, got %v", tt.wantErr, err)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckRouteWithRegexRouter(t *testing.T) {
+	// Routers such as gorilla/mux match parameters using their regular expressions.
+	spec, _, err := NewOpenAPI("Test", "", "http://localhost/api", "1.0.0", t.TempDir(), nil, func(Endpoint, http.Handler) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = defineRoute(spec, "getPet", "GET", "/pets/{id:[0-9]+}"); err != nil {
+		t.Fatal(err)
+	}
+	if err = defineRoute(spec, "getPetByName", "GET", "/pets/{name:[a-z]+}"); err != nil {
+		t.Fatalf("expected routes which differ by their regular expressions to be allowed, got %v", err)
+	}
+	if err = defineRoute(spec, "getPetByCode", "GET", "/pets/{code:[0-9a-f]+}"); err == nil || !strings.Contains(err.Error(), "is ambiguous with") {
+		t.Fatalf("expected overlapping routes to be rejected, got %v", err)
+	}
+}
+
+func TestPatternsOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"[0-9]+", "[0-9]{4}-[0-9]{2}", false},
+		{"[0-9]+", "[0-9]{4}", true},
+		{"a|b", "b|c", true},
+		{"a|b", "c|d", false},
+		{"(?i)abc", "ABC", true},
+		{"(?i)k", "K", true},
+		{"[a-z]*", "[0-9]*", true},
+		{"[a-z]+", "[0-9]*", false},
+		{`\bx`, "x", true},
+		{".+", "[^a]", true},
+		{"a/b", "a.b", false},
+		{"(", "a", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+" "+tt.b, func(t *testing.T) {
+			if got := patternsOverlap(tt.a, tt.b); got != tt.want {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			if got := patternsOverlap(tt.b, tt.a); got != tt.want {
+				t.Fatalf("expected %v in reverse, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package oas
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// A route to an endpoint in the route table of the spec.
+type route struct {
+	endpoint *endpointObject
+	method   string
+	regex    *regexp.Regexp
+	segments []PathSegment
+	// The rank of each segment of the path, where lower ranks take precedence.
+	ranks []int
+}
+
+// The ranks of path segments.
+const (
+	rankStatic = iota
+	rankPatternParam
+	rankParam
+)
+
+// Add a route to the endpoint, keeping the routes sorted by precedence.
+func (o *openAPI) addRoute(e *endpointObject) error {
+	regex, err := regexp.Compile("^" + e.regexPath.String() + "$")
+	if err != nil {
+		return err
+	}
+	o.routes = append(o.routes, newRoute(e, regex))
+	sort.SliceStable(o.routes, func(i, j int) bool {
+		return o.routes[i].precedes(o.routes[j])
+	})
+	return nil
+}
+
+func newRoute(e *endpointObject, regex *regexp.Regexp) route {
+	r := route{
+		endpoint: e,
+		method:   strings.ToUpper(e.method),
+		regex:    regex,
+		segments: PathSegments(e.path),
+	}
+	for _, segment := range r.segments {
+		switch {
+		case segment.Param == "":
+			r.ranks = append(r.ranks, rankStatic)
+		case segment.Pattern != "":
+			r.ranks = append(r.ranks, rankPatternParam)
+		default:
+			r.ranks = append(r.ranks, rankParam)
+		}
+	}
+	return r
+}
+
+// Check if the route takes precedence over another route.
+// Static segments take precedence over parameters, and parameters with patterns over those without,
+// comparing segments from left to right. Routes are then ordered by their number of segments (most first),
+// then by path.
+func (r route) precedes(other route) bool {
+	for i := 0; i < len(r.ranks) && i < len(other.ranks); i++ {
+		if r.ranks[i] != other.ranks[i] {
+			return r.ranks[i] < other.ranks[i]
+		}
+	}
+	if len(r.ranks) != len(other.ranks) {
+		return len(r.ranks) > len(other.ranks)
+	}
+	return r.endpoint.path < other.endpoint.path
+}
+
+// Serve a request using the endpoint whose route matches the request.
+// The routes of the spec include the path of its server URL.
+//
+// Requests for paths without any route receive a 404 problem, and requests for paths whose routes do not allow the
+// method receive a 405 problem with an Allow header.
+// HEAD requests are served by GET routes without a response body, and OPTIONS requests respond with an Allow header,
+// unless routes are defined for them.
+func (o *openAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var allowed []string
+	seen := make(map[string]bool)
+	var get *route
+	for i := range o.routes {
+		rt := &o.routes[i]
+		if !rt.regex.MatchString(r.URL.Path) {
+			continue
+		}
+		if rt.method == r.Method {
+			rt.endpoint.Call(w, r)
+			return
+		}
+		if rt.method == http.MethodGet && get == nil {
+			get = rt
+		}
+		if !seen[rt.method] {
+			seen[rt.method] = true
+			allowed = append(allowed, rt.method)
+		}
+	}
+
+	if len(allowed) == 0 {
+		writeProblem(w, NewProblem(404, "No endpoint exists at the path: "+r.URL.Path))
+		return
+	}
+	if r.Method == http.MethodHead && get != nil {
+		get.endpoint.Call(headResponseWriter{w}, r)
+		return
+	}
+
+	if seen[http.MethodGet] && !seen[http.MethodHead] {
+		allowed = append(allowed, http.MethodHead)
+	}
+	if !seen[http.MethodOptions] {
+		allowed = append(allowed, http.MethodOptions)
+	}
+	sort.Strings(allowed)
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeProblem(w, NewProblem(405, "The method "+r.Method+" is not allowed at the path: "+r.URL.Path))
+}
+
+// Write a problem as the response, outside of any endpoint.
+func writeProblem(w http.ResponseWriter, p *Problem) {
+	b, err := EncodeJSON(p, 0)
+	if err != nil {
+		w.WriteHeader(p.Status)
+		return
+	}
+	w.Header().Set("Content-Type", MimeProblemJson)
+	w.WriteHeader(p.Status)
+	_, _ = w.Write(b)
+}
+
+// Discards the body of a response to a HEAD request.
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
@@ -0,0 +1,73 @@
+package oas
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServeHTTP(t *testing.T) {
+	spec := newTestSpec(t)
+	define := func(operationId, method, path string) {
+		e := spec.NewEndpoint(operationId, method, path, "", "", nil).Response(200, "The operation.", nil)
+		if strings.Contains(path, "{id") {
+			e = e.Parameter("path", "id", "", true, map[string]interface{}{"type": "string"}, reflect.String)
+		}
+		e.MustDefine(func(Data) (interface{}, error) {
+			return operationId, nil
+		})
+	}
+	define("listPets", "GET", "/pets")
+	define("createPet", "POST", "/pets")
+	define("getPet", "GET", "/pets/{id}")
+	define("deletePet", "DELETE", "/pets/{id}")
+	define("getMyPets", "GET", "/pets/mine")
+	define("getOrder", "GET", "/orders/{id:[0-9]+}")
+	define("optionsOrders", "OPTIONS", "/orders")
+	define("putOrders", "PUT", "/orders")
+
+	tests := []struct {
+		name      string
+		method    string
+		target    string
+		status    int
+		body      string
+		allow     string
+		emptyBody bool
+	}{
+		{"static route", "GET", "/api/pets", 200, `"listPets"`, "", false},
+		{"method of the same path", "POST", "/api/pets", 200, `"createPet"`, "", false},
+		{"parameter route", "GET", "/api/pets/7", 200, `"getPet"`, "", false},
+		{"static segment before a parameter", "GET", "/api/pets/mine", 200, `"getMyPets"`, "", false},
+		{"method of a parameter route", "DELETE", "/api/pets/mine", 200, `"deletePet"`, "", false},
+		{"pattern parameter", "GET", "/api/orders/12", 200, `"getOrder"`, "", false},
+		{"pattern parameter mismatch", "GET", "/api/orders/abc", 404, "", "", false},
+		{"unknown path", "GET", "/api/owners", 404, "", "", false},
+		{"path outside of the server url", "GET", "/pets", 404, "", "", false},
+		{"trailing slash", "GET", "/api/pets/", 404, "", "", false},
+		{"method not allowed", "DELETE", "/api/pets", 405, "", "GET, HEAD, OPTIONS, POST", false},
+		{"method not allowed across routes", "PUT", "/api/pets/mine", 405, "", "DELETE, GET, HEAD, OPTIONS", false},
+		{"head of a get route", "HEAD", "/api/pets", 200, "", "", true},
+		{"options without a route", "OPTIONS", "/api/pets/7", 204, "", "DELETE, GET, HEAD, OPTIONS", true},
+		{"options with a route", "OPTIONS", "/api/orders", 200, `"optionsOrders"`, "", false},
+		{"method not allowed with an options route", "GET", "/api/orders", 405, "", "OPTIONS, PUT", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(spec, tt.method, tt.target, nil, nil)
+			assertStatus(t, w, tt.status)
+			if allow := w.Header().Get("Allow"); allow != tt.allow {
+				t.Fatalf("expected Allow %q, got %q", tt.allow, allow)
+			}
+			body := strings.TrimSpace(w.Body.String())
+			switch {
+			case tt.emptyBody && body != "":
+				t.Fatalf("expected no body, got %s", body)
+			case tt.body != "" && body != tt.body:
+				t.Fatalf("expected body %s, got %s", tt.body, body)
+			case tt.status >= 400 && w.Header().Get("Content-Type") != MimeProblemJson:
+				t.Fatalf("expected a problem, got %s", w.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,448 @@
+package oas
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+	schemaNameRegex = regexp.MustCompile(`\W+`)
+)
+
+// Generate a JSON Schema for the Go type of the object.
+// Named struct types are registered as schemas of their own (in the validator and in the documentation components)
+// and are referenced by name using the {Name} syntax.
+//
+// Struct fields are read as follows:
+//   - The `json` tag sets the property name. Properties are required unless tagged with omitempty or of pointer type.
+//   - Embedded structs have their properties merged into the parent.
+//   - Pointers are nullable, and so are slices and maps which are not tagged with omitempty, since nil is marshaled as null.
+//     The same schema validates request bodies, so requests may also send null for these fields, which are read as nil.
+//   - The `validate` tag supports required, omitempty, min, max, len, oneof, email, url, uri, and uuid.
+//   - The `oas` tag supports comma separated key=value pairs for description, format, pattern, enum (values separated by |),
+//     minimum, maximum, minLength, maxLength, minItems, maxItems, example, and default.
+//     Commas within values must be escaped with a backslash, written as \\, inside of the struct tag.
+func (o *openAPI) SchemaOf(object interface{}) (interface{}, error) {
+	s, err := o.typeSchema(reflect.TypeOf(object))
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to generate schema for %T", object)
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to marshal generated schema for %T", object)
+	}
+	return json.RawMessage(b), nil
+}
+
+func (o *openAPI) typeSchema(t reflect.Type) (map[string]interface{}, error) {
+	if t == nil {
+		return map[string]interface{}{}, nil
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}, nil
+	case t == problemType:
+		return map[string]interface{}{"$ref": "{" + ProblemSchemaName + "}"}, nil
+	case t.Kind() == reflect.Ptr:
+		s, err := o.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return nullableSchema(s), nil
+	case t.Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(jsonMarshalerType):
+		return map[string]interface{}{}, nil
+	case t.Implements(textMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType):
+		return map[string]interface{}{"type": "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16:
+		return map[string]interface{}{"type": "integer"}, nil
+	case reflect.Int32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}, nil
+	case reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}, nil
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}, nil
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number", "format": "double"}, nil
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, nil
+	case reflect.Interface:
+		return map[string]interface{}{}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}, nil
+		}
+		items, err := o.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		s := map[string]interface{}{"type": "array", "items": items}
+		if t.Kind() == reflect.Array {
+			s["minItems"] = t.Len()
+			s["maxItems"] = t.Len()
+		}
+		return s, nil
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		default:
+			return nil, errors.New("unsupported map key type: " + t.Key().String())
+		}
+		values, err := o.typeSchema(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if t.Name() == "" {
+			return o.structSchema(t)
+		}
+		name, err := o.registerStruct(t)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"$ref": "{" + name + "}"}, nil
+	}
+	return nil, errors.New("unsupported type for schema generation: " + t.String())
+}
+
+// Register a named struct type as a schema, returning the name it can be referenced by.
+func (o *openAPI) registerStruct(t reflect.Type) (string, error) {
+	if name, ok := o.schemaTypes[t]; ok {
+		return name, nil
+	}
+	name := schemaNameRegex.ReplaceAllString(t.Name(), "_")
+	for other, otherName := range o.schemaTypes {
+		if otherName == name {
+			return "", fmt.Errorf("schema name %s is used by both %s and %s", name, other, t)
+		}
+	}
+	if _, ok := o.validatorBuilder.GetSchemas()[name]; ok {
+		return "", fmt.Errorf("schema name %s for %s is already in use", name, t)
+	}
+
+	// Register before generating so that recursive types resolve to a reference.
+	o.schemaTypes[t] = name
+	s, err := o.structSchema(t)
+	if err != nil {
+		delete(o.schemaTypes, t)
+		return "", err
+	}
+	b, err := json.Marshal(s)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to marshal schema for "+name)
+	}
+	if err = o.validatorBuilder.AddSchema(name, b); err != nil {
+		return "", errors.WithMessage(err, "failed to add schema for "+name)
+	}
+	if o.doc.Components.Schemas[name], err = docSchema(b); err != nil {
+		return "", err
+	}
+	o.docChanged()
+	return name, nil
+}
+
+func (o *openAPI) structSchema(t reflect.Type) (map[string]interface{}, error) {
+	properties := make(map[string]interface{})
+	required := make([]string, 0, t.NumField())
+	embedded := make([]reflect.Type, 0)
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := splitTag(tag, ",")
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		s, err := o.typeSchema(f.Type)
+		if err != nil {
+			return nil, errors.WithMessage(err, "field "+f.Name)
+		}
+		isRequired := f.Type.Kind() != reflect.Ptr && !containsString(opts, "omitempty")
+		if isRequired && marshalsNilAsNull(f.Type) {
+			// Nil slices and maps are marshaled as null, so the field is always present but may be null.
+			s = nullableSchema(s)
+		}
+		if s, isRequired, err = applyFieldTags(s, f, isRequired); err != nil {
+			return nil, errors.WithMessage(err, "field "+f.Name)
+		}
+		properties[name] = s
+		if isRequired {
+			required = append(required, name)
+		}
+	}
+
+	// Fields of the outer struct take precedence over those of embedded structs.
+	for _, et := range embedded {
+		s, err := o.structSchema(et)
+		if err != nil {
+			return nil, err
+		}
+		for name, p := range s["properties"].(map[string]interface{}) {
+			if _, ok := properties[name]; ok {
+				continue
+			}
+			properties[name] = p
+			if embeddedRequired, _ := s["required"].([]string); containsString(embeddedRequired, name) {
+				required = append(required, name)
+			}
+		}
+	}
+
+	s := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s, nil
+}
+
+// Apply the `validate` and `oas` struct tags of a field to its schema.
+func applyFieldTags(s map[string]interface{}, f reflect.StructField, isRequired bool) (map[string]interface{}, bool, error) {
+	validate, hasValidate := f.Tag.Lookup("validate")
+	oasTag, hasOas := f.Tag.Lookup("oas")
+	if !hasValidate && !hasOas {
+		return s, isRequired, nil
+	}
+
+	// Keywords next to a $ref are ignored, so constraints must be placed alongside it instead.
+	if _, ok := s["$ref"]; ok {
+		s = map[string]interface{}{"allOf": []interface{}{s}}
+	}
+	typ := schemaType(s)
+
+	if hasValidate {
+		for _, rule := range strings.Split(validate, ",") {
+			key, value := rule, ""
+			if i := strings.Index(rule, "="); i >= 0 {
+				key, value = rule[:i], rule[i+1:]
+			}
+			switch key {
+			case "required":
+				isRequired = true
+			case "omitempty":
+				isRequired = false
+			case "min", "max", "len":
+				n, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, false, errors.New("validate tag expected a number for " + key + ": " + value)
+				}
+				keys := map[string][]string{"min": {"min"}, "max": {"max"}, "len": {"min", "max"}}[key]
+				for _, k := range keys {
+					switch typ {
+					case "string":
+						s[k+"Length"] = int(n)
+					case "array":
+						s[k+"Items"] = int(n)
+					case "object":
+						s[k+"Properties"] = int(n)
+					default:
+						s[map[string]string{"min": "minimum", "max": "maximum"}[k]] = n
+					}
+				}
+			case "oneof":
+				values := strings.Fields(value)
+				enum := make([]interface{}, 0, len(values))
+				for _, v := range values {
+					enum = append(enum, stringToJSONType(typ, v))
+				}
+				s["enum"] = enum
+			case "email":
+				s["format"] = "email"
+			case "url", "uri":
+				s["format"] = "uri"
+			case "uuid":
+				s["format"] = "uuid"
+			}
+		}
+	}
+
+	if hasOas {
+		for _, item := range splitEscaped(oasTag) {
+			i := strings.Index(item, "=")
+			if i < 0 {
+				return nil, false, errors.New("oas tag expected key=value, found: " + item)
+			}
+			key, value := item[:i], item[i+1:]
+			switch key {
+			case "description", "format", "pattern":
+				s[key] = value
+			case "enum":
+				values := strings.Split(value, "|")
+				enum := make([]interface{}, 0, len(values))
+				for _, v := range values {
+					enum = append(enum, stringToJSONType(typ, v))
+				}
+				s["enum"] = enum
+			case "example", "default":
+				s[key] = stringToJSONType(typ, value)
+			case "minimum", "maximum":
+				n, err := strconv.ParseFloat(value, 64)
+				if err != nil {
+					return nil, false, errors.New("oas tag expected a number for " + key + ": " + value)
+				}
+				s[key] = n
+			case "minLength", "maxLength", "minItems", "maxItems":
+				n, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, false, errors.New("oas tag expected an integer for " + key + ": " + value)
+				}
+				s[key] = n
+			default:
+				return nil, false, errors.New("unknown oas tag key: " + key)
+			}
+		}
+	}
+	return s, isRequired, nil
+}
+
+// Check if the zero value of a type is marshaled as null, other than by a pointer.
+func marshalsNilAsNull(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Map {
+		return false
+	}
+	return !t.Implements(jsonMarshalerType) && !reflect.PtrTo(t).Implements(jsonMarshalerType) &&
+		!t.Implements(textMarshalerType) && !reflect.PtrTo(t).Implements(textMarshalerType)
+}
+
+// Make a schema also accept null.
+func nullableSchema(s map[string]interface{}) map[string]interface{} {
+	if _, ok := s["$ref"]; ok {
+		return map[string]interface{}{"anyOf": []interface{}{s, map[string]interface{}{"type": "null"}}}
+	}
+	if typ, ok := s["type"].(string); ok {
+		s["type"] = []interface{}{typ, "null"}
+	}
+	return s
+}
+
+// Convert the nullable forms of JSON Schema into the nullable keyword used by OpenAPI 3.0.
+func nullableToOpenAPI(v map[string]interface{}) {
+	if types, ok := v["type"].([]interface{}); ok {
+		nonNull := make([]interface{}, 0, len(types))
+		for _, t := range types {
+			if t != "null" {
+				nonNull = append(nonNull, t)
+			}
+		}
+		if len(nonNull) < len(types) {
+			v["nullable"] = true
+		}
+		if len(nonNull) == 1 {
+			v["type"] = nonNull[0]
+		} else {
+			v["type"] = nonNull
+		}
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		options, ok := v[key].([]interface{})
+		if !ok {
+			continue
+		}
+		nonNull := make([]interface{}, 0, len(options))
+		for _, o := range options {
+			if m, ok := o.(map[string]interface{}); !ok || len(m) != 1 || m["type"] != "null" {
+				nonNull = append(nonNull, o)
+			}
+		}
+		if len(nonNull) == len(options) {
+			continue
+		}
+		v["nullable"] = true
+		if len(nonNull) == 1 {
+			delete(v, key)
+			v["allOf"] = nonNull
+		} else {
+			v[key] = nonNull
+		}
+	}
+}
+
+// Get the non-null type of a schema, or an empty string if there is none.
+func schemaType(s map[string]interface{}) string {
+	switch typ := s["type"].(type) {
+	case string:
+		return typ
+	case []interface{}:
+		for _, t := range typ {
+			if t != "null" {
+				return fmt.Sprint(t)
+			}
+		}
+	}
+	return ""
+}
+
+// Split a tag into its first item and the rest of its items.
+func splitTag(tag, sep string) (string, []string) {
+	parts := strings.Split(tag, sep)
+	return parts[0], parts[1:]
+}
+
+// Split a tag on commas which have not been escaped with a backslash.
+func splitEscaped(tag string) []string {
+	items := make([]string, 0, 2)
+	var current strings.Builder
+	for i := 0; i < len(tag); i++ {
+		switch {
+		case tag[i] == '\\' && i+1 < len(tag) && tag[i+1] == ',':
+			current.WriteByte(',')
+			i++
+		case tag[i] == ',':
+			items = append(items, current.String())
+			current.Reset()
+		default:
+			current.WriteByte(tag[i])
+		}
+	}
+	if current.Len() > 0 {
+		items = append(items, current.String())
+	}
+	return items
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
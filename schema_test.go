@@ -0,0 +1,100 @@
+package oas
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type itemList struct {
+	Items  []string       `json:"items"`
+	Counts map[string]int `json:"counts"`
+	Tags   []string       `json:"tags,omitempty"`
+	Raw    []byte         `json:"raw"`
+	Name   string         `json:"name"`
+}
+
+func TestStructSchemaNilSlicesAndMaps(t *testing.T) {
+	spec := newTestSpec(t)
+	raw, err := spec.SchemaOf(struct {
+		itemList
+	}{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var s struct {
+		Properties map[string]struct {
+			Type interface{} `json:"type"`
+		} `json:"properties"`
+		Required []string `json:"required"`
+	}
+	if err = json.Unmarshal(raw.(json.RawMessage), &s); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		property string
+		wantType interface{}
+		required bool
+	}{
+		{"items", []interface{}{"array", "null"}, true},
+		{"counts", []interface{}{"object", "null"}, true},
+		{"tags", "array", false},
+		{"raw", []interface{}{"string", "null"}, true},
+		{"name", "string", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.property, func(t *testing.T) {
+			if typ := s.Properties[tt.property].Type; !reflect.DeepEqual(typ, tt.wantType) {
+				t.Fatalf("expected type %v, got %v", tt.wantType, typ)
+			}
+			if required := containsString(s.Required, tt.property); required != tt.required {
+				t.Fatalf("expected required %v, got %v", tt.required, required)
+			}
+		})
+	}
+}
+
+func TestResponseWithNilSlicePassesValidation(t *testing.T) {
+	spec := newTestSpec(t)
+	spec.SetResponseValidation(ResponseValidationFail, 1)
+	MustHandle(spec.NewEndpoint("listItems", "GET", "/items", "List items.", "", nil),
+		func(Data, struct{}) (itemList, error) {
+			return itemList{Name: "empty"}, nil
+		})
+
+	w := serve(spec, "GET", "/api/items", nil, nil)
+	assertStatus(t, w, 200)
+}
+
+func TestRequestWithNullSliceAndMap(t *testing.T) {
+	spec := newTestSpec(t)
+	var received itemList
+	MustHandle(spec.NewEndpoint("createItems", "POST", "/items", "Create items.", "", nil),
+		func(_ Data, req struct{ Body itemList }) (interface{}, error) {
+			received = req.Body
+			return nil, nil
+		})
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"values", `{"items":["a"],"counts":{"a":1},"raw":"","name":"a"}`, 200},
+		{"null slice and map", `{"items":null,"counts":null,"raw":null,"name":"a"}`, 200},
+		{"missing slice", `{"counts":{},"raw":"","name":"a"}`, 400},
+		{"null string", `{"items":[],"counts":{},"raw":"","name":null}`, 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = itemList{}
+			w := serve(spec, "POST", "/api/items", strings.NewReader(tt.body), map[string]string{"Content-Type": "application/json"})
+			assertStatus(t, w, tt.status)
+			if tt.status == 200 && received.Name != "a" {
+				t.Fatalf("expected the body to be read, got %+v", received)
+			}
+		})
+	}
+}
@@ -0,0 +1,225 @@
+package oas
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/oasm"
+	"sort"
+	"strings"
+)
+
+// The credentials of a request for a single security scheme.
+type Credentials struct {
+	// The name of the security scheme in Components.SecuritySchemes.
+	SchemeName string
+	Scheme     oasm.SecurityScheme
+	// The API key, or the token for bearer, oauth2, and openIdConnect schemes.
+	Value string
+	// The user name and password for basic schemes.
+	Username string
+	Password string
+}
+
+// Authenticates the credentials of a request for a security scheme, returning the principal that they belong to.
+// Scopes are those listed by the security requirement which is being evaluated.
+// Returned errors reject the credentials with a 401 problem, unless the error is a *Problem, which is sent as is.
+// A 403 problem should be returned for valid credentials which lack permission, such as missing scopes.
+type SecurityHandler func(data Data, credentials Credentials, scopes []string) (principal interface{}, err error)
+
+func (o *openAPI) SetSecurityHandler(schemeName string, handler SecurityHandler) {
+	if handler == nil {
+		delete(o.securityHandlers, schemeName)
+	} else {
+		o.securityHandlers[schemeName] = handler
+	}
+}
+
+// Get the security requirements which apply to the endpoint.
+// Those of the operation replace the global requirements, as in the OpenAPI Specification,
+// so an operation which declares an empty list of requirements requires no security.
+func (e *endpointObject) securityRequirements() []oasm.SecurityRequirement {
+	if e.securityDeclared {
+		return e.doc.Security
+	}
+	return e.spec.doc.Security
+}
+
+// Check if the endpoint declares that it requires no security.
+func (e *endpointObject) optsOutOfSecurity() bool {
+	return e.securityDeclared && len(e.doc.Security) == 0
+}
+
+// Check that every scheme required by the endpoint is defined and has a security handler,
+// if any security handlers have been set. Otherwise, security requirements are only documented.
+func (e *endpointObject) checkSecurityHandlers() error {
+	e.enforceSecurity = len(e.spec.securityHandlers) > 0
+	if !e.enforceSecurity {
+		return nil
+	}
+	for _, requirement := range e.securityRequirements() {
+		for _, name := range sortedSchemeNames(requirement) {
+			if _, ok := e.spec.doc.Components.SecuritySchemes[name]; !ok {
+				return errors.New("security scheme is not defined in components: " + e.doc.OperationId + " " + name)
+			}
+			if _, ok := e.spec.securityHandlers[name]; !ok {
+				return errors.New("no security handler is set for the security scheme: " + e.doc.OperationId + " " + name)
+			}
+		}
+	}
+	return nil
+}
+
+// Authenticate the request using the security requirements of the endpoint, setting the principals on the data.
+// The request is authenticated if any one requirement has all of its schemes satisfied.
+// Requirements are not enforced if no security handlers were set when the endpoint was defined.
+func (e *endpointObject) authenticate(data *Data) error {
+	requirements := e.securityRequirements()
+	if !e.enforceSecurity || len(requirements) == 0 {
+		return nil
+	}
+
+	var failure error
+	for _, requirement := range requirements {
+		principals, err := e.authenticateRequirement(*data, requirement)
+		if err == nil {
+			data.Principals = principals
+			for _, name := range sortedSchemeNames(requirement) {
+				data.Principal = principals[name]
+				break
+			}
+			return nil
+		}
+		if failure == nil || securityFailureRank(err) > securityFailureRank(failure) {
+			failure = err
+		}
+	}
+
+	if p, ok := errors.Cause(failure).(*Problem); ok && p.Status == 401 {
+		e.setAuthenticateHeaders(data, requirements)
+	}
+	return failure
+}
+
+// Authenticate the request using all of the schemes of a single security requirement.
+func (e *endpointObject) authenticateRequirement(data Data, requirement oasm.SecurityRequirement) (map[string]interface{}, error) {
+	principals := make(map[string]interface{}, len(requirement))
+	for _, name := range sortedSchemeNames(requirement) {
+		scheme, ok := e.spec.doc.Components.SecuritySchemes[name]
+		if !ok {
+			return nil, errors.New("security scheme is not defined in components: " + name)
+		}
+		// The handler may have been removed after the endpoint was defined.
+		handler, ok := e.spec.securityHandlers[name]
+		if !ok {
+			return nil, errors.New("no security handler is set for the security scheme: " + name)
+		}
+		credentials, ok := readCredentials(data, name, scheme)
+		if !ok {
+			return nil, NewProblem(401, "Missing credentials for "+name+".")
+		}
+		principal, err := handler(data, credentials, requirement[name])
+		if err != nil {
+			if p := (*Problem)(nil); errors.As(err, &p) {
+				return nil, p
+			}
+			return nil, NewProblem(401, fmt.Sprint("Invalid credentials for ", name, ": ", err.Error()))
+		}
+		principals[name] = principal
+	}
+	return principals, nil
+}
+
+// Read the credentials for a security scheme from the request, returning false if they are missing.
+func readCredentials(data Data, name string, scheme oasm.SecurityScheme) (Credentials, bool) {
+	credentials := Credentials{SchemeName: name, Scheme: scheme}
+	switch strings.ToLower(scheme.Type) {
+	case "apikey":
+		switch scheme.In {
+		case oasm.InHeader:
+			credentials.Value = data.Req.Header.Get(scheme.Name)
+		case oasm.InQuery:
+			credentials.Value = data.Req.URL.Query().Get(scheme.Name)
+		case oasm.InCookie:
+			if c, err := data.Req.Cookie(scheme.Name); err == nil {
+				credentials.Value = c.Value
+			}
+		}
+		return credentials, credentials.Value != ""
+	case "http":
+		if strings.EqualFold(scheme.Scheme, "basic") {
+			var ok bool
+			credentials.Username, credentials.Password, ok = data.Req.BasicAuth()
+			return credentials, ok
+		}
+		credentials.Value, _ = authorization(data, scheme.Scheme)
+		return credentials, credentials.Value != ""
+	case "oauth2", "openidconnect":
+		credentials.Value, _ = authorization(data, "bearer")
+		return credentials, credentials.Value != ""
+	}
+	return credentials, false
+}
+
+// Read the value of the Authorization header for the authentication scheme.
+func authorization(data Data, authScheme string) (string, bool) {
+	h := data.Req.Header.Get("Authorization")
+	i := strings.IndexByte(h, ' ')
+	if i < 0 || !strings.EqualFold(h[:i], authScheme) {
+		return "", false
+	}
+	return strings.TrimSpace(h[i+1:]), true
+}
+
+// Set a WWW-Authenticate header for each of the HTTP authentication schemes of the requirements.
+func (e *endpointObject) setAuthenticateHeaders(data *Data, requirements []oasm.SecurityRequirement) {
+	seen := make(map[string]bool)
+	for _, requirement := range requirements {
+		for _, name := range sortedSchemeNames(requirement) {
+			scheme := e.spec.doc.Components.SecuritySchemes[name]
+			var challenge string
+			switch strings.ToLower(scheme.Type) {
+			case "http":
+				if scheme.Scheme != "" {
+					challenge = strings.ToUpper(scheme.Scheme[:1]) + strings.ToLower(scheme.Scheme[1:])
+				}
+			case "oauth2", "openidconnect":
+				challenge = "Bearer"
+			}
+			if challenge != "" && !seen[challenge] {
+				seen[challenge] = true
+				data.ResWriter.Header().Add("WWW-Authenticate", challenge+` realm="`+e.spec.doc.Info.Title+`"`)
+			}
+		}
+	}
+}
+
+// Rank the failures of security requirements, so that the most relevant one is reported.
+// Configuration errors are ranked above forbidden requests, which are ranked above unauthenticated requests.
+func securityFailureRank(err error) int {
+	if p, ok := errors.Cause(err).(*Problem); ok {
+		return p.Status
+	}
+	return 1000
+}
+
+func sortedSchemeNames(requirement oasm.SecurityRequirement) []string {
+	names := make([]string, 0, len(requirement))
+	for name := range requirement {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Document the responses for failed authentication, if the endpoint enforces security requirements.
+func (e *endpointObject) documentSecurity() {
+	if !e.enforceSecurity || len(e.securityRequirements()) == 0 {
+		return
+	}
+	if _, ok := e.doc.Responses.Codes[401]; !ok {
+		e.doc.Responses.Codes[401] = oasm.Response{Description: "Authentication is required."}
+	}
+	if _, ok := e.doc.Responses.Codes[403]; !ok {
+		e.doc.Responses.Codes[403] = oasm.Response{Description: "The credentials do not have permission."}
+	}
+}
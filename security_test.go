@@ -0,0 +1,166 @@
+package oas
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/tjbrockmeyer/oasm"
+)
+
+func apiKeySpec(t *testing.T) OpenAPI {
+	t.Helper()
+	spec := newTestSpec(t)
+	doc := spec.Doc()
+	doc.Components.SecuritySchemes = map[string]oasm.SecurityScheme{
+		"key": {Type: "apiKey", Name: "X-Key", In: oasm.InHeader},
+	}
+	doc.Security = []oasm.SecurityRequirement{{"key": {}}}
+	spec.SetSecurityHandler("key", func(_ Data, c Credentials, _ []string) (interface{}, error) {
+		if c.Value != "secret" {
+			return nil, errors.New("wrong key")
+		}
+		return "user", nil
+	})
+	return spec
+}
+
+func TestNoSecurityOverridesGlobalRequirements(t *testing.T) {
+	spec := apiKeySpec(t)
+	h := func(Data) (interface{}, error) { return "ok", nil }
+	spec.NewEndpoint("private", "GET", "/private", "", "", nil).Response(200, "ok", nil).MustDefine(h)
+	spec.NewEndpoint("public", "GET", "/public", "", "", nil).Response(200, "ok", nil).NoSecurity().MustDefine(h)
+
+	assertStatus(t, serve(spec, "GET", "/api/private", nil, nil), 401)
+	assertStatus(t, serve(spec, "GET", "/api/private", nil, map[string]string{"X-Key": "secret"}), 200)
+	assertStatus(t, serve(spec, "GET", "/api/public", nil, nil), 200)
+
+	b, err := spec.(*openAPI).docJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `"operationId":"public","responses"`) || !strings.Contains(string(b), `"security":[]`) {
+		t.Fatalf("expected the public operation to document empty security: %s", b)
+	}
+}
+
+func TestLoadOpenAPIEmptySecurity(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "api.yaml")
+	err := os.WriteFile(file, []byte(`
+openapi: 3.0.3
+info: {title: Test, version: 1.0.0}
+servers: [{url: http://localhost/api}]
+security: [{key: []}]
+components:
+  securitySchemes:
+    key: {type: apiKey, name: X-Key, in: header}
+paths:
+  /private:
+    get:
+      operationId: private
+      responses: {'200': {description: ok}}
+  /public:
+    get:
+      operationId: public
+      security: []
+      responses: {'200': {description: ok}}
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := func(Data) (interface{}, error) { return "ok", nil }
+	security := map[string]SecurityHandler{
+		"key": func(Data, Credentials, []string) (interface{}, error) { return "user", nil },
+	}
+	spec, _, err := LoadOpenAPI(file, map[string]HandlerFunc{"private": h, "public": h}, security, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertStatus(t, serve(spec, "GET", "/api/private", nil, nil), 401)
+	assertStatus(t, serve(spec, "GET", "/api/public", nil, nil), 200)
+}
+
+func TestSecuritySchemesWithoutHandlers(t *testing.T) {
+	h := func(Data) (interface{}, error) { return "ok", nil }
+	tests := []struct {
+		name      string
+		security  []oasm.SecurityRequirement
+		headers   map[string]string
+		defineErr string
+		status    int
+	}{
+		{"scheme without a handler", []oasm.SecurityRequirement{{"token": {}}}, nil,
+			"no security handler is set for the security scheme: getPets token", 0},
+		{"scheme with and without a handler", []oasm.SecurityRequirement{{"key": {}, "token": {}}}, nil,
+			"no security handler is set for the security scheme: getPets token", 0},
+		{"other requirement without a handler", []oasm.SecurityRequirement{{"token": {}}, {"key": {}}}, nil,
+			"no security handler is set for the security scheme: getPets token", 0},
+		{"undefined scheme", []oasm.SecurityRequirement{{"other": {}}}, nil,
+			"security scheme is not defined in components: getPets other", 0},
+		{"scheme with a handler", nil, map[string]string{"X-Key": "secret"}, "", 200},
+		{"scheme with a handler without credentials", nil, nil, "", 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := apiKeySpec(t)
+			spec.Doc().Components.SecuritySchemes["token"] = oasm.SecurityScheme{Type: "http", Scheme: "bearer"}
+			e := spec.NewEndpoint("getPets", "GET", "/pets", "", "", nil).Response(200, "ok", nil)
+			if tt.security != nil {
+				e = e.NoSecurity()
+				for _, requirement := range tt.security {
+					e = e.Security(requirement)
+				}
+			}
+			_, err := e.Define(h)
+			if tt.defineErr != "" {
+				if err == nil || err.Error() != tt.defineErr {
+					t.Fatalf("expected the error %q, got %v", tt.defineErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertStatus(t, serve(spec, "GET", "/api/pets", nil, tt.headers), tt.status)
+		})
+	}
+}
+
+func TestSecurityDocumentedOnlyWithoutHandlers(t *testing.T) {
+	h := func(Data) (interface{}, error) { return "ok", nil }
+	tests := []struct {
+		name     string
+		handlers bool
+		status   int
+		codes    []int
+	}{
+		{"no handlers", false, 200, nil},
+		{"handlers", true, 401, []int{401, 403}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := apiKeySpec(t)
+			if !tt.handlers {
+				spec.SetSecurityHandler("key", nil)
+			}
+			e := spec.NewEndpoint("getPets", "GET", "/pets", "", "", nil).Response(200, "ok", nil).MustDefine(h)
+
+			assertStatus(t, serve(spec, "GET", "/api/pets", nil, nil), tt.status)
+			codes := e.Doc().Responses.Codes
+			if len(codes) != 1+len(tt.codes) {
+				t.Fatalf("expected the responses 200 and %v, got %v", tt.codes, codes)
+			}
+			for _, code := range tt.codes {
+				if _, ok := codes[code]; !ok {
+					t.Fatalf("expected the response %v to be documented, got %v", code, codes)
+				}
+			}
+			if len(spec.Doc().Security) != 1 {
+				t.Fatalf("expected the security requirements to stay documented, got %v", spec.Doc().Security)
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package oas
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// The file name of the spec as YAML, as served by the file server.
+const specYAMLFileName = "openapi.yaml"
+
+// The encodings of the spec, which are cached until the spec changes.
+// The spec is marshaled again only when its generation has changed, which happens whenever it may have been modified,
+// and the files are encoded again only when its JSON differs from that of the cached files.
+type specFiles struct {
+	o          *openAPI
+	mu         sync.Mutex
+	generation uint64
+	json       []byte
+	hash       [sha256.Size]byte
+	files      map[string]specFile
+}
+
+type specFile struct {
+	contentType string
+	body        []byte
+	etag        string
+}
+
+func (o *openAPI) SetPrettySpec(pretty bool) {
+	o.prettySpec = pretty
+	o.docChanged()
+}
+
+// Record that the doc may have been modified, so that the spec is marshaled again the next time it is served.
+func (o *openAPI) docChanged() {
+	o.docGeneration.Add(1)
+}
+
+// Get the spec as JSON (openapi.json) or YAML (openapi.yaml), encoding it if it has changed since it was last served.
+func (s *specFiles) get(name string) (specFile, error) {
+	indent := 0
+	if s.o.prettySpec {
+		indent = s.o.jsonIndent
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if generation := s.o.docGeneration.Load(); s.files == nil || generation != s.generation {
+		b, err := s.o.docJSON()
+		if err != nil {
+			return specFile{}, err
+		}
+		if hash := sha256.Sum256(b); s.files == nil || hash != s.hash {
+			s.files = make(map[string]specFile, 2)
+			s.json = b
+			s.hash = hash
+		}
+		s.generation = generation
+	}
+	key := fmt.Sprint(name, " ", indent)
+	if f, ok := s.files[key]; ok {
+		return f, nil
+	}
+
+	var err error
+	b := s.json
+	f := specFile{contentType: "application/json; charset=UTF-8", body: b}
+	switch {
+	case name == specYAMLFileName:
+		f.contentType = MimeYaml + "; charset=UTF-8"
+		if f.body, err = specYAML(b, indent); err != nil {
+			return specFile{}, err
+		}
+	case indent > 0:
+		var buf bytes.Buffer
+		if err = json.Indent(&buf, b, "", string(bytes.Repeat([]byte{' '}, indent))); err != nil {
+			return specFile{}, err
+		}
+		f.body = buf.Bytes()
+	}
+	sum := sha256.Sum256(f.body)
+	f.etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+	s.files[key] = f
+	return f, nil
+}
+
+// Convert the JSON encoding of the spec into YAML, keeping the order of its keys.
+func specYAML(b []byte, indent int) ([]byte, error) {
+	// JSON is valid YAML, so it is read as a node and then written in block style.
+	var node yaml.Node
+	if err := yaml.Unmarshal(b, &node); err != nil {
+		return nil, err
+	}
+	var setBlockStyle func(n *yaml.Node)
+	setBlockStyle = func(n *yaml.Node) {
+		n.Style = 0
+		for _, child := range n.Content {
+			setBlockStyle(child)
+		}
+	}
+	setBlockStyle(&node)
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	if indent > 0 {
+		encoder.SetIndent(indent)
+	}
+	if err := encoder.Encode(&node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Serve the spec, responding with 304 Not Modified if the request has the current ETag in If-None-Match.
+func (s *specFiles) serve(w http.ResponseWriter, r *http.Request, name string) error {
+	f, err := s.get(name)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("ETag", f.etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(f.body))
+	return nil
+}
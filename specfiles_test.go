@@ -0,0 +1,77 @@
+package oas
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tjbrockmeyer/oasm"
+)
+
+func TestSpecFilesFollowChanges(t *testing.T) {
+	spec, fileServer, err := NewOpenAPI("Test", "", "http://localhost/api", "1.0.0", t.TempDir(), nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := spec.NewEndpoint("getPets", "GET", "/pets", "Get pets.", "", nil).Response(200, "The pets.", nil).MustDefine(func(Data) (interface{}, error) {
+		return nil, nil
+	})
+	get := func(file, etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/docs/"+file, nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		fileServer.ServeHTTP(w, r)
+		return w
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		change   func()
+		changed  bool
+		contains string
+	}{
+		{"unchanged json", specFileName, func() {}, false, `"title":"Test"`},
+		{"unchanged yaml", specYAMLFileName, func() {}, false, "title: Test"},
+		{"info changed through Doc", specFileName, func() { spec.Doc().Info.Title = "Pets" }, true, `"title":"Pets"`},
+		{"yaml of the changed info", specYAMLFileName, func() { spec.Doc().Info.Title = "Pet Store" }, true, "title: Pet Store"},
+		{"unchanged doc is not marshaled again", specFileName, func() {
+			spec.(*openAPI).doc.Info.Title = "Unseen"
+		}, false, `"title":"Pet Store"`},
+		{"change picked up by the next Doc call", specFileName, func() { spec.Doc() }, true, `"title":"Unseen"`},
+		{"setter without a change", specFileName, func() { spec.SetPrettySpec(false) }, false, `"title":"Unseen"`},
+		{"version changed", specFileName, func() { spec.SetOpenAPIVersion(OpenAPIVersion31) }, true, `"openapi":"3.1.0"`},
+		{"webhook added", specFileName, func() {
+			spec.AddWebhook("petAdded", "POST", oasm.Operation{OperationId: "petAdded"})
+		}, true, `"webhooks"`},
+		{"response changed through the endpoint doc", specFileName, func() {
+			r := e.Doc().Responses.Codes[200]
+			r.Description = "All of the pets."
+			e.Doc().Responses.Codes[200] = r
+		}, true, `"description":"All of the pets."`},
+		{"pretty spec", specFileName, func() { spec.SetPrettySpec(true) }, true, "\n  \"openapi\""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := get(tt.file, "")
+			assertStatus(t, before, 200)
+			etag := before.Header().Get("ETag")
+			tt.change()
+			after := get(tt.file, etag)
+			if tt.changed {
+				assertStatus(t, after, 200)
+				if after.Header().Get("ETag") == etag {
+					t.Fatalf("expected the ETag to change from %s", etag)
+				}
+			} else {
+				assertStatus(t, after, 304)
+				after = get(tt.file, "")
+			}
+			if !strings.Contains(after.Body.String(), tt.contains) {
+				t.Fatalf("expected the spec to contain %s: %s", tt.contains, after.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,363 @@
+package oas
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// The schemas of OpenAPI documents, by the version of OpenAPI.
+// The schema of 3.0 is the official schema, and the schema of 3.1 is the official schema adapted for JSON Schema draft 7.
+//
+//go:embed meta-schemas
+var metaSchemaFiles embed.FS
+
+var metaSchemas = struct {
+	sync.Mutex
+	compiled map[string]*gojsonschema.Schema
+}{compiled: make(map[string]*gojsonschema.Schema)}
+
+// Errors of the schema keywords which combine other schemas, which only add detail when nothing else was reported.
+var combinedSchemaErrors = map[string]bool{
+	"number_one_of":  true,
+	"number_any_of":  true,
+	"number_all_of":  true,
+	"condition_then": true,
+	"condition_else": true,
+}
+
+// Determines what happens to the problems found when the spec is validated at startup. See: OpenAPI.ValidateOnStart
+type SpecValidationMode int
+
+const (
+	// The spec is not validated.
+	SpecValidationOff SpecValidationMode = iota
+	// The problems with the spec are logged.
+	SpecValidationLog
+	// The problems with the spec are logged, and then cause a panic, so that an invalid spec stops the process.
+	SpecValidationFail
+)
+
+// A problem with the spec, which was found by OpenAPI.Validate.
+type SpecProblem struct {
+	// A JSON Pointer to the part of the spec which has the problem, such as /paths/~1pets/get/responses/200.
+	// The pointer of the root of the spec is empty.
+	Pointer string
+	Message string
+}
+
+func (p SpecProblem) String() string {
+	return "#" + p.Pointer + ": " + p.Message
+}
+
+// All of the problems with the spec, which are returned as an error by OpenAPI.Validate.
+type SpecProblems []SpecProblem
+
+func (p SpecProblems) Error() string {
+	lines := make([]string, len(p))
+	for i, problem := range p {
+		lines[i] = problem.String()
+	}
+	return fmt.Sprintf("the spec has %v problem(s):\n\t%s", len(p), strings.Join(lines, "\n\t"))
+}
+
+func (o *openAPI) ValidateOnStart(mode SpecValidationMode) {
+	if mode == SpecValidationOff {
+		return
+	}
+	err := o.Validate()
+	if err == nil {
+		return
+	}
+	log.Println("the openapi spec is invalid:", err)
+	if mode == SpecValidationFail {
+		panic(err)
+	}
+}
+
+func (o *openAPI) Validate() error {
+	b, err := o.docJSON()
+	if err != nil {
+		return err
+	}
+	var doc map[string]interface{}
+	if err = json.Unmarshal(b, &doc); err != nil {
+		return errors.WithMessage(err, "failed to read the spec for validation")
+	}
+
+	name := "meta-schemas/openapi-3.0.json"
+	if o.isOpenAPI31() {
+		name = "meta-schemas/openapi-3.1.json"
+	}
+	problems, err := metaSchemaProblems(name, b)
+	if err != nil {
+		return err
+	}
+	problems = append(problems, responseDescriptionProblems(doc)...)
+	problems = append(problems, tagProblems(doc)...)
+	problems = append(problems, securityProblems(doc)...)
+	problems = append(problems, refProblems(doc, doc, "")...)
+	problems = append(problems, operationIdProblems(doc)...)
+	problems = append(problems, pathParamProblems(doc)...)
+	if len(problems) == 0 {
+		return nil
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Pointer < problems[j].Pointer
+	})
+	return problems
+}
+
+// Validate the spec against the schema of its version of OpenAPI.
+func metaSchemaProblems(name string, b []byte) (SpecProblems, error) {
+	metaSchemas.Lock()
+	schema, ok := metaSchemas.compiled[name]
+	if !ok {
+		s, err := metaSchemaFiles.ReadFile(name)
+		if err != nil {
+			metaSchemas.Unlock()
+			return nil, errors.WithMessage(err, "failed to read the openapi schema")
+		}
+		if schema, err = gojsonschema.NewSchema(gojsonschema.NewBytesLoader(s)); err != nil {
+			metaSchemas.Unlock()
+			return nil, errors.WithMessage(err, "failed to compile the openapi schema")
+		}
+		metaSchemas.compiled[name] = schema
+	}
+	metaSchemas.Unlock()
+
+	result, err := schema.Validate(gojsonschema.NewBytesLoader(b))
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to validate the spec")
+	}
+	var problems SpecProblems
+	seen := make(map[SpecProblem]bool)
+	for _, resultError := range result.Errors() {
+		message := strings.TrimPrefix(resultError.Description(), resultError.Field()+" ")
+		problem := SpecProblem{Pointer: contextPointer(resultError.Context()), Message: message}
+		if combinedSchemaErrors[resultError.Type()] && hasDetailedError(result.Errors(), problem.Pointer) {
+			continue
+		}
+		if !seen[problem] {
+			seen[problem] = true
+			problems = append(problems, problem)
+		}
+	}
+	return problems, nil
+}
+
+// Check if any error was reported within the part of the spec, other than those of combined schemas.
+func hasDetailedError(resultErrors []gojsonschema.ResultError, pointer string) bool {
+	for _, resultError := range resultErrors {
+		p := contextPointer(resultError.Context())
+		if !combinedSchemaErrors[resultError.Type()] && (p == pointer || strings.HasPrefix(p, pointer+"/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Convert the context of a validation error into a JSON Pointer.
+func contextPointer(ctx *gojsonschema.JsonContext) string {
+	tokens := strings.Split(ctx.String("\x00"), "\x00")[1:]
+	return jsonPointer(tokens...)
+}
+
+// Create a JSON Pointer from its reference tokens.
+func jsonPointer(tokens ...string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(token, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
+
+// Call the function for every operation of the paths and webhooks of the spec.
+func forEachOperation(doc map[string]interface{}, f func(pointer string, pathItem, operation map[string]interface{})) {
+	for _, section := range []string{"paths", "webhooks", webhooksExtension} {
+		pathItems := mapOf(doc[section])
+		for _, p := range sortedKeys(pathItems) {
+			pathItem := mapOf(pathItems[p])
+			for _, method := range operationMethods {
+				if operation, ok := pathItem[method].(map[string]interface{}); ok {
+					f(jsonPointer(section, p, method), pathItem, operation)
+				}
+			}
+		}
+	}
+}
+
+// Find responses with empty descriptions. Missing descriptions are found by the schema.
+func responseDescriptionProblems(doc map[string]interface{}) SpecProblems {
+	var problems SpecProblems
+	check := func(pointer string, responses map[string]interface{}) {
+		for _, status := range sortedKeys(responses) {
+			response := mapOf(responses[status])
+			if description, ok := response["description"].(string); ok && strings.TrimSpace(description) == "" {
+				problems = append(problems, SpecProblem{pointer + jsonPointer(status, "description"), "the response has no description"})
+			}
+		}
+	}
+	forEachOperation(doc, func(pointer string, _, operation map[string]interface{}) {
+		check(pointer+"/responses", mapOf(operation["responses"]))
+	})
+	check("/components/responses", mapOf(mapOf(doc["components"])["responses"]))
+	return problems
+}
+
+// Find tags of operations which are not declared in the tags of the spec.
+func tagProblems(doc map[string]interface{}) SpecProblems {
+	declared := make(map[string]bool)
+	tags, _ := doc["tags"].([]interface{})
+	for _, tag := range tags {
+		declared[stringOf(mapOf(tag)["name"])] = true
+	}
+	var problems SpecProblems
+	forEachOperation(doc, func(pointer string, _, operation map[string]interface{}) {
+		tags, _ := operation["tags"].([]interface{})
+		for i, tag := range tags {
+			if !declared[stringOf(tag)] {
+				problems = append(problems, SpecProblem{pointer + jsonPointer("tags", fmt.Sprint(i)),
+					fmt.Sprintf("the tag %q is not declared in the tags of the spec", stringOf(tag))})
+			}
+		}
+	})
+	return problems
+}
+
+// Find security requirements which name security schemes that are not in the components of the spec.
+func securityProblems(doc map[string]interface{}) SpecProblems {
+	schemes := mapOf(mapOf(doc["components"])["securitySchemes"])
+	var problems SpecProblems
+	check := func(pointer string, security interface{}) {
+		requirements, _ := security.([]interface{})
+		for i, requirement := range requirements {
+			for _, name := range sortedKeys(mapOf(requirement)) {
+				if _, ok := schemes[name]; !ok {
+					problems = append(problems, SpecProblem{pointer + jsonPointer(fmt.Sprint(i), name),
+						fmt.Sprintf("the security scheme %q is not defined in the components of the spec", name)})
+				}
+			}
+		}
+	}
+	check("/security", doc["security"])
+	forEachOperation(doc, func(pointer string, _, operation map[string]interface{}) {
+		check(pointer+"/security", operation["security"])
+	})
+	return problems
+}
+
+// Find local references which do not point to a part of the spec.
+// Examples and the values of schemas are not part of the spec, so their references are not checked.
+func refProblems(doc map[string]interface{}, v interface{}, pointer string) SpecProblems {
+	var problems SpecProblems
+	switch v := v.(type) {
+	case []interface{}:
+		for i, item := range v {
+			problems = append(problems, refProblems(doc, item, pointer+jsonPointer(fmt.Sprint(i)))...)
+		}
+	case map[string]interface{}:
+		for _, k := range sortedKeys(v) {
+			switch k {
+			case "example", "enum", "const":
+				continue
+			}
+			if ref, ok := v[k].(string); ok && k == "$ref" && strings.HasPrefix(ref, "#") {
+				target, err := url.PathUnescape(ref[1:])
+				if err == nil {
+					_, err = resolvePointer(doc, target)
+				}
+				if err != nil {
+					problems = append(problems, SpecProblem{pointer + "/$ref", fmt.Sprintf("the reference %q does not exist", ref)})
+				}
+				continue
+			}
+			problems = append(problems, refProblems(doc, v[k], pointer+jsonPointer(k))...)
+		}
+	}
+	return problems
+}
+
+// Find operationIds which are used by more than one operation.
+func operationIdProblems(doc map[string]interface{}) SpecProblems {
+	first := make(map[string]string)
+	var problems SpecProblems
+	forEachOperation(doc, func(pointer string, _, operation map[string]interface{}) {
+		id, ok := operation["operationId"].(string)
+		if !ok {
+			return
+		}
+		if other, ok := first[id]; ok {
+			problems = append(problems, SpecProblem{pointer + "/operationId",
+				fmt.Sprintf("the operationId %q is also used by #%s", id, other)})
+		} else {
+			first[id] = pointer
+		}
+	})
+	return problems
+}
+
+// Find path parameters which are not in the path of their operation, and parameters of the path which are not documented.
+func pathParamProblems(doc map[string]interface{}) SpecProblems {
+	var problems SpecProblems
+	paths := mapOf(doc["paths"])
+	for _, p := range sortedKeys(paths) {
+		var names []string
+		inPath := make(map[string]bool)
+		for _, segment := range PathSegments(p) {
+			if segment.Param != "" {
+				names = append(names, segment.Param)
+				inPath[segment.Param] = true
+			}
+		}
+		// Get the names of the path parameters of the list, reporting those which are not in the path.
+		documentedBy := func(pointer string, params interface{}) map[string]bool {
+			documented := make(map[string]bool)
+			list, _ := params.([]interface{})
+			for i, param := range list {
+				resolved, err := resolveLocalRef(doc, param)
+				if err != nil {
+					problems = append(problems, SpecProblem{pointer + jsonPointer(fmt.Sprint(i)), err.Error()})
+					continue
+				}
+				param := mapOf(resolved)
+				if param["in"] != "path" {
+					continue
+				}
+				name := stringOf(param["name"])
+				documented[name] = true
+				if !inPath[name] {
+					problems = append(problems, SpecProblem{pointer + jsonPointer(fmt.Sprint(i)),
+						fmt.Sprintf("the path parameter %q is not in the path", name)})
+				}
+			}
+			return documented
+		}
+
+		pathItem := mapOf(paths[p])
+		forPath := documentedBy(jsonPointer("paths", p, "parameters"), pathItem["parameters"])
+		for _, method := range operationMethods {
+			operation, ok := pathItem[method].(map[string]interface{})
+			if !ok {
+				continue
+			}
+			pointer := jsonPointer("paths", p, method)
+			forOperation := documentedBy(pointer+"/parameters", operation["parameters"])
+			for _, name := range names {
+				if !forPath[name] && !forOperation[name] {
+					problems = append(problems, SpecProblem{pointer,
+						fmt.Sprintf("the path parameter %q is not documented by the operation", name)})
+				}
+			}
+		}
+	}
+	return problems
+}
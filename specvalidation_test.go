@@ -0,0 +1,64 @@
+package oas
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tjbrockmeyer/oasm"
+)
+
+func TestValidate(t *testing.T) {
+	h := func(Data) (interface{}, error) { return nil, nil }
+	spec := newTestSpec(t)
+	spec.Doc().Tags = []oasm.Tag{{Name: "items"}}
+	spec.NewEndpoint("getItem", "GET", "/items/{id}", "", "", []string{"items"}).
+		Parameter("path", "id", "The item id.", true, map[string]interface{}{"type": "string"}, reflect.String).
+		Response(200, "The item.", map[string]interface{}{"type": "object"}).MustDefine(h)
+	for _, version := range []OpenAPIVersion{OpenAPIVersion30, OpenAPIVersion31} {
+		spec.SetOpenAPIVersion(version)
+		if err := spec.Validate(); err != nil {
+			t.Fatalf("expected %s spec to be valid: %v", version, err)
+		}
+	}
+
+	e := spec.NewEndpoint("listItems", "GET", "/items", "", "", []string{"other"}).
+		Response(200, "", map[string]interface{}{"type": "array"}).
+		Security(map[string][]string{"undefined": {}}).MustDefine(h)
+	// A reference which the validator would not accept can only be added to the documentation.
+	e.Doc().Responses.Codes[200].Content["application/json"] = oasm.MediaType{
+		Schema: map[string]interface{}{"$ref": "#/components/schemas/Missing"},
+	}
+	spec.Doc().Paths["/items"].Methods["get"] = *e.Doc()
+	var problems SpecProblems
+	if err := spec.Validate(); !errors.As(err, &problems) {
+		t.Fatalf("expected problems, got %v", err)
+	}
+	want := map[SpecProblem]bool{
+		{"/paths/~1items/get/responses/200/description", "the response has no description"}:                                                       true,
+		{"/paths/~1items/get/responses/200/content/application~1json/schema/$ref", `the reference "#/components/schemas/Missing" does not exist`}: true,
+		{"/paths/~1items/get/security/0/undefined", `the security scheme "undefined" is not defined in the components of the spec`}:               true,
+		{"/paths/~1items/get/tags/0", `the tag "other" is not declared in the tags of the spec`}:                                                  true,
+	}
+	for _, p := range problems {
+		delete(want, p)
+	}
+	if len(want) > 0 {
+		t.Fatalf("missing problems %v in %v", want, problems)
+	}
+}
+
+func TestValidateOnStart(t *testing.T) {
+	spec := newTestSpec(t)
+	spec.NewEndpoint("list", "GET", "/items", "", "", []string{"undeclared"}).
+		Response(200, "ok", nil).MustDefine(func(Data) (interface{}, error) { return nil, nil })
+
+	spec.ValidateOnStart(SpecValidationOff)
+	spec.ValidateOnStart(SpecValidationLog)
+	defer func() {
+		if _, ok := recover().(SpecProblems); !ok {
+			t.Fatal("expected a panic with the problems of the spec")
+		}
+	}()
+	spec.ValidateOnStart(SpecValidationFail)
+}
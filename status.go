@@ -0,0 +1,133 @@
+package oas
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/oasm"
+)
+
+// Determines what happens when an endpoint responds with a status code which is not documented on its operation.
+type UndeclaredStatusMode int
+
+const (
+	// Responses with undeclared status codes are sent without being reported. (Default)
+	UndeclaredStatusAllow UndeclaredStatusMode = iota
+	// Responses with undeclared status codes are reported after they are sent.
+	UndeclaredStatusLog
+	// Responses with undeclared status codes are reported, and are replaced by a 500 problem.
+	UndeclaredStatusFail
+)
+
+// Receives the status codes of responses which were not documented on their operation.
+type UndeclaredStatusHandler func(data Data, status int)
+
+func (o *openAPI) SetUndeclaredStatusMode(mode UndeclaredStatusMode) {
+	o.undeclaredStatusMode = mode
+}
+
+func (o *openAPI) SetUndeclaredStatusHandler(handler UndeclaredStatusHandler) {
+	o.undeclaredStatusHandler = handler
+}
+
+func (e *endpointObject) ResponseRange(class int, description string, schema interface{}) EndpointDeclaration {
+	if class < 1 || class > 5 {
+		e.err = errors.New(fmt.Sprint("response range must be from 1 to 5: ", e.doc.OperationId, " ", class))
+		return e
+	}
+	r, schemaRef, err := e.newResponse(rangeKey(class), description, schema)
+	if err != nil {
+		e.err = err
+		return e
+	}
+	if schemaRef != "" {
+		e.responseRangeSchemaRefs[class] = schemaRef
+	}
+	e.responseRanges[class] = r
+	return e
+}
+
+func (e *endpointObject) DefaultResponse(description string, schema interface{}) EndpointDeclaration {
+	r, schemaRef, err := e.newResponse("default", description, schema)
+	if err != nil {
+		e.err = err
+		return e
+	}
+	e.defaultResponseSchemaRef = schemaRef
+	e.doc.Responses.Default = &r
+	return e
+}
+
+// The key of a range of status codes in the responses of an operation.
+func rangeKey(class int) string {
+	return fmt.Sprint(class, "XX")
+}
+
+// Find the name of the schema for responses with the status code, using the schema of the status code,
+// then its range, then the default response.
+func (e *endpointObject) responseSchemaRef(status int) (string, bool) {
+	if _, ok := e.doc.Responses.Codes[status]; ok {
+		schemaRef, ok := e.responseSchemaRefs[status]
+		return schemaRef, ok
+	}
+	if _, ok := e.responseRanges[status/100]; ok {
+		schemaRef, ok := e.responseRangeSchemaRefs[status/100]
+		return schemaRef, ok
+	}
+	return e.defaultResponseSchemaRef, e.defaultResponseSchemaRef != ""
+}
+
+// Check if a response is documented on the operation by its status code, its range, or the default response.
+func (e *endpointObject) statusDeclared(status int) bool {
+	if _, ok := e.doc.Responses.Codes[status]; ok {
+		return true
+	}
+	if _, ok := e.responseRanges[status/100]; ok {
+		return true
+	}
+	return e.doc.Responses.Default != nil
+}
+
+// Identifies an operation of the doc by its path and method.
+type operationKey struct {
+	path, method string
+}
+
+// Keep the response ranges of an operation with the doc, since they cannot be represented by oasm.Responses.
+func (o *openAPI) documentResponseRanges(path, method string, ranges map[int]oasm.Response) {
+	key := operationKey{path, method}
+	if len(ranges) == 0 {
+		delete(o.responseRanges, key)
+		return
+	}
+	if o.responseRanges == nil {
+		o.responseRanges = make(map[operationKey]map[int]oasm.Response)
+	}
+	o.responseRanges[key] = make(map[int]oasm.Response, len(ranges))
+	for class, r := range ranges {
+		o.responseRanges[key][class] = r
+	}
+}
+
+// Add response ranges to an operation in the JSON encoding of the doc.
+func addResponseRanges(operation map[string]interface{}, ranges map[int]oasm.Response) {
+	responses, _ := operation["responses"].(map[string]interface{})
+	if responses == nil {
+		return
+	}
+	for class, r := range ranges {
+		responses[rangeKey(class)] = r
+	}
+}
+
+// Report a response with an undeclared status code, returning true if it should be replaced.
+func (e *endpointObject) checkStatusDeclared(data Data, res Response) bool {
+	mode := e.spec.undeclaredStatusMode
+	if mode == UndeclaredStatusAllow || e.statusDeclared(res.Status) {
+		return false
+	}
+	e.printError(fmt.Errorf("response status %v is not declared on the operation", res.Status))
+	if e.spec.undeclaredStatusHandler != nil {
+		e.spec.undeclaredStatusHandler(data, res.Status)
+	}
+	return mode == UndeclaredStatusFail
+}
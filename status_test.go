@@ -0,0 +1,124 @@
+package oas
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestUndeclaredStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		declare    func(EndpointDeclaration) EndpointDeclaration
+		res        Response
+		status     int
+		undeclared bool
+	}{
+		{"declared code", func(e EndpointDeclaration) EndpointDeclaration { return e }, Response{Status: 200, Body: "ok"}, 200, false},
+		{"undeclared code", func(e EndpointDeclaration) EndpointDeclaration { return e }, Response{Status: 202, Body: "ok"}, 500, true},
+		{"problem without a default response", func(e EndpointDeclaration) EndpointDeclaration { return e },
+			Response{Status: 409, Body: NewProblem(409, "")}, 500, true},
+		{"code in a declared range", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.ResponseRange(4, "Client errors.", nil)
+		}, Response{Status: 409, Body: NewProblem(409, "")}, 409, false},
+		{"code outside of a declared range", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.ResponseRange(4, "Client errors.", nil)
+		}, Response{Status: 503, Body: NewProblem(503, "")}, 500, true},
+		{"code covered by a declared default", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.DefaultResponse("An unexpected error occurred.", nil)
+		}, Response{Status: 503, Body: NewProblem(503, "")}, 503, false},
+		{"body with a declared default", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.DefaultResponse("Anything else.", nil)
+		}, Response{Status: 202, Body: "ok"}, 202, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTestSpec(t)
+			spec.SetUndeclaredStatusMode(UndeclaredStatusFail)
+			var reported []int
+			spec.SetUndeclaredStatusHandler(func(_ Data, status int) {
+				reported = append(reported, status)
+			})
+			tt.declare(spec.NewEndpoint("getPets", "GET", "/pets", "", "", nil).Response(200, "The pets.", nil)).
+				MustDefine(func(Data) (interface{}, error) {
+					return tt.res, nil
+				})
+			assertStatus(t, serve(spec, "GET", "/api/pets", nil, nil), tt.status)
+			if undeclared := len(reported) > 0; undeclared != tt.undeclared {
+				t.Fatalf("expected undeclared %v, got the reported statuses %v", tt.undeclared, reported)
+			}
+		})
+	}
+}
+
+func TestResponseRangesInSpec(t *testing.T) {
+	tests := []struct {
+		name      string
+		declare   func(EndpointDeclaration) EndpointDeclaration
+		responses []string
+		problems  []string
+	}{
+		{"no ranges or default", func(e EndpointDeclaration) EndpointDeclaration { return e },
+			[]string{"200"}, nil},
+		{"range", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.ResponseRange(4, "Client errors.", nil)
+		}, []string{"200", "4XX"}, []string{"4XX"}},
+		{"success range", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.ResponseRange(2, "Successes.", nil)
+		}, []string{"200", "2XX"}, nil},
+		{"default", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.DefaultResponse("An unexpected error occurred.", nil)
+		}, []string{"200", "default"}, []string{"default"}},
+		{"range and default", func(e EndpointDeclaration) EndpointDeclaration {
+			return e.ResponseRange(5, "Server errors.", nil).DefaultResponse("Anything else.", nil)
+		}, []string{"200", "5XX", "default"}, []string{"5XX", "default"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := newTestSpec(t)
+			tt.declare(spec.NewEndpoint("getPets", "GET", "/pets", "", "", nil).Response(200, "The pets.", nil)).
+				MustDefine(func(Data) (interface{}, error) {
+					return nil, nil
+				})
+			b, err := spec.(*openAPI).docJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var doc struct {
+				Paths map[string]map[string]struct {
+					Responses map[string]struct {
+						Content map[string]interface{}
+					}
+				}
+			}
+			if err = json.Unmarshal(b, &doc); err != nil {
+				t.Fatal(err)
+			}
+			responses := doc.Paths["/pets"]["get"].Responses
+			if len(responses) != len(tt.responses) {
+				t.Fatalf("expected the responses %v, got %s", tt.responses, b)
+			}
+			for _, code := range tt.responses {
+				if _, ok := responses[code]; !ok {
+					t.Fatalf("expected the response %s, got %s", code, b)
+				}
+			}
+			for _, code := range tt.problems {
+				if _, ok := responses[code].Content[MimeProblemJson]; !ok {
+					t.Fatalf("expected the response %s to document problems, got %s", code, b)
+				}
+			}
+
+			var buf bytes.Buffer
+			if err = spec.ExportMarkdown(&buf); err != nil {
+				t.Fatal(err)
+			}
+			for _, code := range tt.responses {
+				if !strings.Contains(buf.String(), code) {
+					t.Fatalf("expected the export to contain the response %s: %s", code, buf.String())
+				}
+			}
+		})
+	}
+}
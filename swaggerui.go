@@ -0,0 +1,73 @@
+package oas
+
+import (
+	"embed"
+	"html/template"
+	"io/fs"
+)
+
+// The file name of the spec, as served by the Swagger UI file server.
+const specFileName = "openapi.json"
+
+//go:embed swagger-dist
+var swaggerDist embed.FS
+
+var swaggerUIIndex = template.Must(template.ParseFS(swaggerDist, "swagger-dist/index.html"))
+
+// The settings of the Swagger UI which is served by the file server of the spec.
+type SwaggerUIOptions struct {
+	// The URL of the spec shown by the UI. (Default: the spec, served by the file server)
+	SpecURL string
+	// Update the URL of the page when opening tags and operations, so that they can be linked to.
+	DeepLinking bool
+	// Keep authorizations when the page is reloaded.
+	PersistAuthorization bool
+	// The client id which is filled in when authorizing with OAuth2 schemes.
+	OAuth2ClientId string
+	// The URL that OAuth2 authorization redirects to. (Default: the oauth2-redirect.html page of the UI)
+	OAuth2RedirectURL string
+}
+
+// Get the default settings of the Swagger UI.
+func DefaultSwaggerUIOptions() SwaggerUIOptions {
+	return SwaggerUIOptions{
+		DeepLinking: true,
+	}
+}
+
+func (o *openAPI) SetSwaggerUIOptions(options SwaggerUIOptions) {
+	o.swaggerUIOptions = options
+}
+
+// The values used by the index.html template of the Swagger UI.
+type swaggerUIIndexData struct {
+	// The settings passed to SwaggerUIBundle.
+	Config map[string]interface{}
+	// The settings passed to initOAuth, if any.
+	OAuth map[string]interface{}
+}
+
+func (options SwaggerUIOptions) indexData() swaggerUIIndexData {
+	data := swaggerUIIndexData{
+		Config: map[string]interface{}{
+			"url":                  "./" + specFileName,
+			"deepLinking":          options.DeepLinking,
+			"persistAuthorization": options.PersistAuthorization,
+		},
+	}
+	if options.SpecURL != "" {
+		data.Config["url"] = options.SpecURL
+	}
+	if options.OAuth2RedirectURL != "" {
+		data.Config["oauth2RedirectUrl"] = options.OAuth2RedirectURL
+	}
+	if options.OAuth2ClientId != "" {
+		data.OAuth = map[string]interface{}{"clientId": options.OAuth2ClientId}
+	}
+	return data
+}
+
+// Get the files of the Swagger UI.
+func swaggerUIFiles() (fs.FS, error) {
+	return fs.Sub(swaggerDist, "swagger-dist")
+}
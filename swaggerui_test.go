@@ -0,0 +1,75 @@
+package oas
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSwaggerUIIndexData(t *testing.T) {
+	tests := []struct {
+		name    string
+		options SwaggerUIOptions
+		config  map[string]interface{}
+		oauth   map[string]interface{}
+	}{
+		{"defaults", DefaultSwaggerUIOptions(),
+			map[string]interface{}{"url": "./openapi.json", "deepLinking": true, "persistAuthorization": false}, nil},
+		{"spec url", SwaggerUIOptions{SpecURL: "/spec.json", PersistAuthorization: true},
+			map[string]interface{}{"url": "/spec.json", "deepLinking": false, "persistAuthorization": true}, nil},
+		{"oauth2", SwaggerUIOptions{OAuth2ClientId: "client", OAuth2RedirectURL: "https://example.com/callback"},
+			map[string]interface{}{"url": "./openapi.json", "deepLinking": false, "persistAuthorization": false,
+				"oauth2RedirectUrl": "https://example.com/callback"},
+			map[string]interface{}{"clientId": "client"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.options.indexData()
+			if !reflect.DeepEqual(data.Config, tt.config) {
+				t.Fatalf("expected the config %v, got %v", tt.config, data.Config)
+			}
+			if !reflect.DeepEqual(data.OAuth, tt.oauth) {
+				t.Fatalf("expected the oauth settings %v, got %v", tt.oauth, data.OAuth)
+			}
+		})
+	}
+}
+
+func TestSwaggerUIIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  SwaggerUIOptions
+		contains []string
+		excludes []string
+	}{
+		{"defaults", DefaultSwaggerUIOptions(),
+			[]string{`"deepLinking":true`, `"url":"./openapi.json"`}, []string{"initOAuth"}},
+		{"oauth2", SwaggerUIOptions{OAuth2ClientId: "client"},
+			[]string{`ui.initOAuth({"clientId":"client"})`}, nil},
+		{"escaped spec url", SwaggerUIOptions{SpecURL: "</script><script>alert(1)</script>"},
+			[]string{`"url":"\u003c/script\u003e\u003cscript\u003ealert(1)`}, []string{"<script>alert(1)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, fileServer, err := NewOpenAPI("Test", "", "http://localhost/api", "1.0.0", t.TempDir(), nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec.SetSwaggerUIOptions(tt.options)
+			w := httptest.NewRecorder()
+			fileServer.ServeHTTP(w, httptest.NewRequest("GET", "/docs/", nil))
+			assertStatus(t, w, 200)
+			for _, s := range tt.contains {
+				if !strings.Contains(w.Body.String(), s) {
+					t.Fatalf("expected the page to contain %s: %s", s, w.Body.String())
+				}
+			}
+			for _, s := range tt.excludes {
+				if strings.Contains(w.Body.String(), s) {
+					t.Fatalf("expected the page not to contain %s: %s", s, w.Body.String())
+				}
+			}
+		})
+	}
+}
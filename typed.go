@@ -0,0 +1,210 @@
+package oas
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/oasm"
+	"reflect"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	uuidType     = reflect.TypeOf(UUID{})
+	responseType = reflect.TypeOf(Response{})
+)
+
+// The tags of request struct fields which bind them to parameters.
+var parameterTags = []string{oasm.InPath, oasm.InQuery, oasm.InHeader, oasm.InCookie}
+
+// A field of a request struct and the part of the request it is bound to.
+type fieldBinding struct {
+	index []int
+	in    string
+	name  string
+}
+
+// Define the endpoint using a handler with typed request and response values.
+//
+// The request type must be a struct. Its fields are bound to parameters using `path`, `query`, `header`, and `cookie`
+// tags which hold the parameter name, and a field named Body is bound to the request body.
+// Each bound field is documented from its type (see: OpenAPI.SchemaOf), using the `validate` and `oas` tags for its
+// schema, and the `description` tag for its description.
+// Path parameters are always required, and other parameters are required when tagged with `validate:"required"`.
+// A Body which is not a pointer is required.
+//
+// The response type is documented as the schema of the 200 response, unless it is interface{} or Response.
+func Handle[Req any, Resp any](endpoint EndpointDeclaration, handler func(Data, Req) (Resp, error)) (Endpoint, error) {
+	reqType := reflect.TypeOf((*Req)(nil)).Elem()
+	if reqType.Kind() != reflect.Struct {
+		return nil, errors.New("request type must be a struct: " + reqType.String())
+	}
+
+	formats := defaultParamTypeFormats()
+	if e, ok := endpoint.(*endpointObject); ok {
+		formats = e.spec.paramTypeFormats
+	}
+	bindings := make([]fieldBinding, 0, reqType.NumField())
+	for i := 0; i < reqType.NumField(); i++ {
+		f := reqType.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if f.Name == "Body" {
+			bodyType := f.Type
+			if bodyType.Kind() == reflect.Ptr {
+				bodyType = bodyType.Elem()
+			}
+			endpoint.RequestBody(f.Tag.Get("description"), f.Type.Kind() != reflect.Ptr, nil, reflect.Zero(bodyType).Interface())
+			bindings = append(bindings, fieldBinding{index: f.Index, in: "body"})
+			continue
+		}
+		for _, in := range parameterTags {
+			name, ok := f.Tag.Lookup(in)
+			if !ok {
+				continue
+			}
+			schema, kind, required, err := parameterSchema(f, in, formats)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "failed to document %s parameter %s", in, name)
+			}
+			endpoint.Parameter(in, name, f.Tag.Get("description"), required, schema, kind)
+			bindings = append(bindings, fieldBinding{index: f.Index, in: in, name: name})
+			break
+		}
+	}
+
+	if respType := reflect.TypeOf((*Resp)(nil)).Elem(); respType.Kind() != reflect.Interface && respType != responseType {
+		endpoint.ResponseOf(200, "Success", reflect.Zero(respType).Interface())
+	}
+
+	return endpoint.Define(func(data Data) (interface{}, error) {
+		var req Req
+		v := reflect.ValueOf(&req).Elem()
+		for _, b := range bindings {
+			var value interface{}
+			switch b.in {
+			case "body":
+				value = data.Body
+			case oasm.InPath:
+				value = data.Params[b.name]
+			case oasm.InQuery:
+				value = data.Query[b.name]
+			case oasm.InHeader:
+				value = data.Headers[b.name]
+			case oasm.InCookie:
+				value = data.Cookies[b.name]
+			}
+			if err := setField(v.FieldByIndex(b.index), value); err != nil {
+				return nil, errors.WithMessagef(err, "failed to bind %s %s", b.in, b.name)
+			}
+		}
+		return handler(data, req)
+	})
+}
+
+// See: Handle
+// Panics if an error occurs.
+func MustHandle[Req any, Resp any](endpoint EndpointDeclaration, handler func(Data, Req) (Resp, error)) Endpoint {
+	e, err := Handle(endpoint, handler)
+	if err != nil {
+		panic(errors.WithMessage(err, "endpoint must handle but failed"))
+	}
+	return e
+}
+
+// Create the schema for a parameter bound to a request struct field, along with its kind and whether it is required.
+func parameterSchema(f reflect.StructField, in string, formats map[reflect.Type]string) (map[string]interface{}, reflect.Kind, bool, error) {
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	s, kind, err := parameterTypeSchema(t, true, formats)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	s, required, err := applyFieldTags(s, f, in == oasm.InPath)
+	return s, kind, required || in == oasm.InPath, err
+}
+
+// Create the schema for a parameter type, which may be an array or map of other parameter types if topLevel is true.
+// Types with a format are strings which are decoded by the decoder of the format.
+func parameterTypeSchema(t reflect.Type, topLevel bool, formats map[reflect.Type]string) (map[string]interface{}, reflect.Kind, error) {
+	if format, ok := formats[t]; ok {
+		return map[string]interface{}{"type": "string", "format": format}, reflect.String, nil
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}, reflect.String, nil
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}, reflect.Bool, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return map[string]interface{}{"type": "integer"}, reflect.Int, nil
+	case reflect.Int64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}, reflect.Int64, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "minimum": 0}, reflect.Uint, nil
+	case reflect.Float32:
+		return map[string]interface{}{"type": "number", "format": "float"}, reflect.Float32, nil
+	case reflect.Float64:
+		return map[string]interface{}{"type": "number"}, reflect.Float64, nil
+	case reflect.Slice:
+		if topLevel {
+			items, _, err := parameterTypeSchema(t.Elem(), false, formats)
+			return map[string]interface{}{"type": "array", "items": items}, reflect.Slice, err
+		}
+	case reflect.Map:
+		if topLevel && t.Key().Kind() == reflect.String {
+			values, _, err := parameterTypeSchema(t.Elem(), false, formats)
+			return map[string]interface{}{"type": "object", "additionalProperties": values}, reflect.Map, err
+		}
+	}
+	return nil, 0, errors.New("unsupported parameter type: " + t.String())
+}
+
+// Set a request struct field from a value of Data, converting it into the type of the field.
+func setField(field reflect.Value, value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	if field.Kind() == reflect.Ptr {
+		ptr := reflect.New(field.Type().Elem())
+		if err := setField(ptr.Elem(), value); err != nil {
+			return err
+		}
+		field.Set(ptr)
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch {
+	case v.Type().AssignableTo(field.Type()):
+		field.Set(v)
+	case v.Kind() == reflect.Ptr && v.Elem().Type().AssignableTo(field.Type()):
+		field.Set(v.Elem())
+	case field.Kind() == reflect.Slice && v.Kind() == reflect.Slice:
+		s := reflect.MakeSlice(field.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			if err := setField(s.Index(i), v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		field.Set(s)
+	case field.Kind() == reflect.Map && v.Kind() == reflect.Map:
+		m := reflect.MakeMapWithSize(field.Type(), v.Len())
+		for _, k := range v.MapKeys() {
+			item := reflect.New(field.Type().Elem()).Elem()
+			if err := setField(item, v.MapIndex(k).Interface()); err != nil {
+				return err
+			}
+			m.SetMapIndex(k.Convert(field.Type().Key()), item)
+		}
+		field.Set(m)
+	case v.Type().ConvertibleTo(field.Type()) && (field.Kind() != reflect.String || v.Kind() == reflect.String):
+		// Numbers are convertible to strings as runes, which is never intended.
+		field.Set(v.Convert(field.Type()))
+	default:
+		return fmt.Errorf("cannot assign %s to %s", v.Type(), field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package oas
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+type sortOrder string
+
+type listRequest struct {
+	Sort  sortOrder `query:"sort"`
+	Limit int       `query:"limit"`
+}
+
+func TestHandleNamedStringParameter(t *testing.T) {
+	spec := newTestSpec(t)
+	MustHandle(spec.NewEndpoint("list", "GET", "/items", "List items.", "", nil),
+		func(_ Data, req listRequest) (map[string]interface{}, error) {
+			return map[string]interface{}{"sort": string(req.Sort), "limit": req.Limit}, nil
+		})
+
+	w := serve(spec, "GET", "/api/items?sort=desc&limit=3", nil, nil)
+	assertStatus(t, w, 200)
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body["sort"] != "desc" || body["limit"] != float64(3) {
+		t.Fatalf("unexpected body: %v", body)
+	}
+}
+
+func TestSetField(t *testing.T) {
+	var s struct {
+		Named  sortOrder
+		String string
+		Int    int64
+	}
+	tests := []struct {
+		name    string
+		field   string
+		value   interface{}
+		wantErr bool
+	}{
+		{"string to named string", "Named", "asc", false},
+		{"string to string", "String", "abc", false},
+		{"float to int", "Int", float64(4), false},
+		{"int to string", "String", 65, true},
+		{"float to named string", "Named", float64(65), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setField(reflectField(&s, tt.field), tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+	if s.Named != "asc" || s.String != "abc" || s.Int != 4 {
+		t.Fatalf("unexpected fields: %+v", s)
+	}
+}
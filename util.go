@@ -1,10 +1,16 @@
 package oas
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/vjsonschema"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path"
 	"strings"
 )
 
@@ -46,6 +52,15 @@ func refNameToSwaggerRef(ref string) string {
 	return "#/components/schemas/" + ref
 }
 
+// Convert a JSON Schema into a schema for the documentation, replacing references.
+// JSON Schema only keywords are converted when the doc is marshaled. See: OpenAPI.SetOpenAPIVersion
+func docSchema(b []byte) (json.RawMessage, error) {
+	if !json.Valid(b) {
+		return nil, errors.New("failed to read schema: invalid JSON")
+	}
+	return json.RawMessage(vjsonschema.SchemaRefReplace(b, refNameToSwaggerRef)), nil
+}
+
 func NewData(w http.ResponseWriter, r *http.Request, e Endpoint) Data {
 	return Data{
 		Req:       r,
@@ -53,6 +68,7 @@ func NewData(w http.ResponseWriter, r *http.Request, e Endpoint) Data {
 		Query:     make(MapAny),
 		Params:    make(MapAny),
 		Headers:   make(MapAny),
+		Cookies:   make(MapAny),
 		Endpoint:  e,
 	}
 }
@@ -70,10 +86,21 @@ type Data struct {
 	Params MapAny
 	// The headers passed in the request which are defined in the documentation for this endpoint.
 	Headers MapAny
+	// The cookies passed in the request which are defined in the documentation for this endpoint.
+	Cookies MapAny
 	// The request body, marshaled into the type of object which was set up on this endpoint during initialization.
 	Body interface{}
+	// The files sent in a multipart/form-data request body.
+	Files map[string][]*multipart.FileHeader
+	// The principal returned by the security handler of the first scheme (by name) of the satisfied security requirement.
+	Principal interface{}
+	// The principals returned by the security handlers of each scheme of the satisfied security requirement.
+	Principals map[string]interface{}
 	// The endpoint which was called.
 	Endpoint Endpoint
+
+	// The multipart form of the request body, whose temporary files are removed once the request has been handled.
+	form *multipart.Form
 }
 
 type Response struct {
@@ -97,28 +124,47 @@ func (m MapAny) GetOrElse(key string, elseValue interface{}) interface{} {
 	return elseValue
 }
 
+// Serves the documentation UI of the spec (see: OpenAPI.SetDocsUI), with the spec as openapi.json and openapi.yaml.
+// Files are found by their name alone, so the server may be mounted at any path.
 type customFileServer struct {
-	dir        http.Dir
-	fileServer http.Handler
-	o          *openAPI
-	cachedSpec []byte
+	o    *openAPI
+	spec *specFiles
 }
 
 func (s *customFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	if s.cachedSpec == nil || len(s.cachedSpec) == 0 {
-		var err error
-		s.cachedSpec, err = json.Marshal(s.o.doc)
-		if err != nil {
+	ui, err := s.o.currentDocsUI()
+	if err != nil {
+		w.WriteHeader(500)
+		log.Println("unable to read the documentation ui:", err)
+		return
+	}
+	name := path.Base(r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") {
+		name = "index.html"
+	}
+	switch {
+	case name == specFileName || name == specYAMLFileName:
+		if err := s.spec.serve(w, r, name); err != nil {
+			w.WriteHeader(500)
+			log.Println("unable to encode the openapi spec:", err)
+		}
+	case name == "index.html":
+		var buf bytes.Buffer
+		if err := ui.Index(&buf, "./"+specFileName); err != nil {
 			w.WriteHeader(500)
-			log.Println("unable to parse openapi spec into json")
+			log.Println("unable to render the documentation ui:", err)
 			return
 		}
+		w.Header().Set("Content-Type", "text/html; charset=UTF-8")
+		_, _ = w.Write(buf.Bytes())
+	case ui.Files != nil:
+		r2 := new(http.Request)
+		*r2 = *r
+		r2.URL = new(url.URL)
+		*r2.URL = *r.URL
+		r2.URL.Path = "/" + name
+		http.FileServer(http.FS(ui.Files)).ServeHTTP(w, r2)
+	default:
+		http.NotFound(w, r)
 	}
-	path := r.URL.Path
-	if strings.HasSuffix(path, "openapi.json") {
-		w.Header().Set("Content-Type", "application/json; charset=UTF-8")
-		_, _ = w.Write(s.cachedSpec)
-		return
-	}
-	s.fileServer.ServeHTTP(w, r)
 }
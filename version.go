@@ -0,0 +1,301 @@
+package oas
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/pkg/errors"
+	"github.com/tjbrockmeyer/oasm"
+	"strings"
+)
+
+// A version of the OpenAPI Specification which the spec is written in.
+type OpenAPIVersion string
+
+const (
+	// OpenAPI 3.0, whose schemas are an extended subset of JSON Schema.
+	// Keywords which it does not support are converted, such as type arrays containing null into nullable,
+	// const into enum, and examples into example. (Default)
+	OpenAPIVersion30 OpenAPIVersion = "3.0.0"
+	// OpenAPI 3.1, whose schemas are JSON Schema 2020-12, so that JSON Schema keywords are written as they are.
+	OpenAPIVersion31 OpenAPIVersion = "3.1.0"
+)
+
+// The dialect of the schemas of a 3.1 spec.
+const JSONSchemaDialect = "https://spec.openapis.org/oas/3.1/dialect/base"
+
+// The extension which holds the webhooks of a 3.0 spec, which has no webhooks field.
+const webhooksExtension = "x-webhooks"
+
+// Keywords of a schema which hold a single subschema.
+var subschemaKeywords = []string{
+	"items", "additionalItems", "additionalProperties", "unevaluatedItems", "unevaluatedProperties",
+	"contains", "propertyNames", "not", "if", "then", "else",
+}
+
+// Keywords of a schema which hold a list of subschemas.
+var subschemaListKeywords = []string{"allOf", "anyOf", "oneOf", "prefixItems", "items"}
+
+// Keywords of a schema which hold subschemas mapped by name.
+var subschemaMapKeywords = []string{"properties", "patternProperties", "dependentSchemas", "$defs", "definitions"}
+
+func (o *openAPI) SetOpenAPIVersion(version OpenAPIVersion) {
+	o.doc.OpenApi = string(version)
+	o.docChanged()
+}
+
+func (o *openAPI) AddWebhook(name, method string, operation oasm.Operation) {
+	if o.webhooks == nil {
+		o.webhooks = make(map[string]map[string]oasm.Operation)
+	}
+	if o.webhooks[name] == nil {
+		o.webhooks[name] = make(map[string]oasm.Operation)
+	}
+	o.webhooks[name][strings.ToLower(method)] = operation
+	o.docChanged()
+}
+
+// Check if the spec is written in OpenAPI 3.1.
+func (o *openAPI) isOpenAPI31() bool {
+	return strings.HasPrefix(o.doc.OpenApi, "3.1")
+}
+
+// Convert the schemas of the marshaled doc for its version of OpenAPI, and add the fields which oasm does not have.
+func (o *openAPI) convertForVersion(b []byte) ([]byte, error) {
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, errors.WithMessage(err, "failed to read the spec for converting its schemas")
+	}
+	is31 := o.isOpenAPI31()
+
+	if len(o.webhooks) > 0 {
+		var webhooks interface{}
+		if err := convertJSON(o.webhooks, &webhooks); err != nil {
+			return nil, errors.WithMessage(err, "failed to marshal the webhooks")
+		}
+		if is31 {
+			doc["webhooks"] = webhooks
+		} else {
+			doc[webhooksExtension] = webhooks
+		}
+	}
+
+	convert := schemaToOpenAPI30
+	if is31 {
+		doc["jsonSchemaDialect"] = JSONSchemaDialect
+		convert = schemaToOpenAPI31
+	}
+	components, _ := doc["components"].(map[string]interface{})
+	schemas, _ := components["schemas"].(map[string]interface{})
+	for _, name := range sortedKeys(schemas) {
+		schema := replaceDefsRefs(schemas[name], refNameToSwaggerRef(name))
+		if !is31 {
+			schema = hoistDefs(schemas, name, schema)
+		}
+		schemas[name] = walkSchema(schema, convert)
+	}
+	walkDocSchemas(doc, convert)
+	return json.Marshal(doc)
+}
+
+// Convert the schemas in the parts of the doc outside of the schemas of the components.
+func walkDocSchemas(v interface{}, convert func(map[string]interface{})) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			walkDocSchemas(item, convert)
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			switch {
+			case k == "schema":
+				v[k] = walkSchema(item, convert)
+			case k == "schemas", k == "example", k == "examples", strings.HasPrefix(k, "x-") && k != webhooksExtension:
+				// Component schemas are converted separately, and examples and extensions are not part of the spec.
+			default:
+				walkDocSchemas(item, convert)
+			}
+		}
+	}
+}
+
+// Convert a schema and all of its subschemas, starting with the deepest.
+func walkSchema(v interface{}, convert func(map[string]interface{})) interface{} {
+	s, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for _, key := range subschemaKeywords {
+		if _, ok := s[key].(map[string]interface{}); ok {
+			s[key] = walkSchema(s[key], convert)
+		}
+	}
+	for _, key := range subschemaListKeywords {
+		if list, ok := s[key].([]interface{}); ok {
+			for i, item := range list {
+				list[i] = walkSchema(item, convert)
+			}
+		}
+	}
+	for _, key := range subschemaMapKeywords {
+		if m, ok := s[key].(map[string]interface{}); ok {
+			for name, item := range m {
+				m[name] = walkSchema(item, convert)
+			}
+		}
+	}
+	convert(s)
+	return s
+}
+
+// Point references to the definitions of a component schema (#/$defs/...) at the component,
+// since they would otherwise be resolved against the root of the spec.
+func replaceDefsRefs(v interface{}, componentRef string) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		for i, item := range v {
+			v[i] = replaceDefsRefs(item, componentRef)
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = replaceDefsRefs(item, componentRef)
+		}
+		if ref, ok := v["$ref"].(string); ok {
+			for _, keyword := range []string{"$defs", "definitions"} {
+				if strings.HasPrefix(ref, "#/"+keyword+"/") {
+					v["$ref"] = componentRef + ref[1:]
+				}
+			}
+		}
+	}
+	return v
+}
+
+// Move the definitions of a component schema into the schemas of the components as {name}.{definition},
+// since OpenAPI 3.0 does not support $defs.
+func hoistDefs(schemas map[string]interface{}, name string, v interface{}) interface{} {
+	s, ok := v.(map[string]interface{})
+	if !ok {
+		return v
+	}
+	for _, keyword := range []string{"$defs", "definitions"} {
+		defs, ok := s[keyword].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		delete(s, keyword)
+		from := refNameToSwaggerRef(name) + "/" + keyword + "/"
+		for _, def := range sortedKeys(defs) {
+			to := refNameToSwaggerRef(name + "." + def)
+			for schemaName := range schemas {
+				schemas[schemaName] = replaceRef(schemas[schemaName], from+def, to)
+			}
+			s = replaceRef(s, from+def, to).(map[string]interface{})
+			defs = replaceRef(defs, from+def, to).(map[string]interface{})
+		}
+		for _, def := range sortedKeys(defs) {
+			schemas[name+"."+def] = walkSchema(hoistDefs(schemas, name+"."+def, defs[def]), schemaToOpenAPI30)
+		}
+	}
+	return s
+}
+
+// Replace the references to a schema, including references to the parts of it.
+func replaceRef(v interface{}, from, to string) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		for i, item := range v {
+			v[i] = replaceRef(item, from, to)
+		}
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = replaceRef(item, from, to)
+		}
+		if ref, ok := v["$ref"].(string); ok && (ref == from || strings.HasPrefix(ref, from+"/")) {
+			v["$ref"] = to + strings.TrimPrefix(ref, from)
+		}
+	}
+	return v
+}
+
+// Convert the JSON Schema keywords of a schema which are not supported by OpenAPI 3.0.
+func schemaToOpenAPI30(s map[string]interface{}) {
+	nullableToOpenAPI(s)
+	if c, ok := s["const"]; ok {
+		delete(s, "const")
+		if _, ok := s["enum"]; !ok {
+			s["enum"] = []interface{}{c}
+		}
+	}
+	if examples, ok := s["examples"].([]interface{}); ok {
+		delete(s, "examples")
+		if _, ok := s["example"]; !ok && len(examples) > 0 {
+			s["example"] = examples[0]
+		}
+	}
+	for limit, exclusive := range map[string]string{"minimum": "exclusiveMinimum", "maximum": "exclusiveMaximum"} {
+		if n, ok := s[exclusive].(json.Number); ok {
+			s[limit] = n
+			s[exclusive] = true
+		}
+	}
+	for _, keyword := range []string{"$schema", "$id", "$comment"} {
+		delete(s, keyword)
+	}
+}
+
+// Convert the OpenAPI 3.0 keywords of a schema into JSON Schema, for schemas which were written for OpenAPI 3.0.
+func schemaToOpenAPI31(s map[string]interface{}) {
+	if nullable, ok := s["nullable"].(bool); ok {
+		delete(s, "nullable")
+		if nullable {
+			nullableToJSONSchema(s)
+		}
+	}
+	for limit, exclusive := range map[string]string{"minimum": "exclusiveMinimum", "maximum": "exclusiveMaximum"} {
+		if isExclusive, ok := s[exclusive].(bool); ok {
+			delete(s, exclusive)
+			if n, ok := s[limit]; ok && isExclusive {
+				delete(s, limit)
+				s[exclusive] = n
+			}
+		}
+	}
+}
+
+// Make a schema which was documented with the nullable keyword of OpenAPI 3.0 accept null in JSON Schema.
+func nullableToJSONSchema(s map[string]interface{}) {
+	nullSchema := map[string]interface{}{"type": "null"}
+	switch typ := s["type"].(type) {
+	case string:
+		if typ != "null" {
+			s["type"] = []interface{}{typ, "null"}
+		}
+		return
+	case []interface{}:
+		for _, t := range typ {
+			if t == "null" {
+				return
+			}
+		}
+		s["type"] = append(typ, "null")
+		return
+	}
+	if allOf, ok := s["allOf"].([]interface{}); ok && len(allOf) == 1 {
+		delete(s, "allOf")
+		s["anyOf"] = []interface{}{allOf[0], nullSchema}
+		return
+	}
+	for _, key := range []string{"anyOf", "oneOf"} {
+		if options, ok := s[key].([]interface{}); ok {
+			s[key] = append(options, nullSchema)
+			return
+		}
+	}
+	// The keywords next to a reference are applied along with it in JSON Schema, so the reference is moved into anyOf.
+	if ref, ok := s["$ref"]; ok {
+		delete(s, "$ref")
+		s["anyOf"] = []interface{}{map[string]interface{}{"$ref": ref}, nullSchema}
+	}
+}
@@ -0,0 +1,225 @@
+package oas
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/tjbrockmeyer/oasm"
+)
+
+// Read a JSON object in the same way as the spec is read for conversion, keeping numbers as json.Number.
+func decodeJSONObject(t *testing.T, s string) map[string]interface{} {
+	t.Helper()
+	var v map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader([]byte(s)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestSchemaConversion(t *testing.T) {
+	tests := []struct {
+		name    string
+		convert func(map[string]interface{})
+		schema  string
+		want    string
+	}{
+		{"3.0 nullable type", schemaToOpenAPI30, `{"type": ["string", "null"]}`, `{"type": "string", "nullable": true}`},
+		{"3.0 nullable anyOf", schemaToOpenAPI30, `{"anyOf": [{"$ref": "#/components/schemas/Pet"}, {"type": "null"}]}`,
+			`{"allOf": [{"$ref": "#/components/schemas/Pet"}], "nullable": true}`},
+		{"3.0 const", schemaToOpenAPI30, `{"const": "a"}`, `{"enum": ["a"]}`},
+		{"3.0 const with enum", schemaToOpenAPI30, `{"const": "a", "enum": ["a", "b"]}`, `{"enum": ["a", "b"]}`},
+		{"3.0 examples", schemaToOpenAPI30, `{"examples": [1, 2]}`, `{"example": 1}`},
+		{"3.0 exclusive minimum", schemaToOpenAPI30, `{"exclusiveMinimum": 0}`, `{"minimum": 0, "exclusiveMinimum": true}`},
+		{"3.0 exclusive maximum", schemaToOpenAPI30, `{"minimum": 0, "exclusiveMaximum": 10.5}`,
+			`{"minimum": 0, "maximum": 10.5, "exclusiveMaximum": true}`},
+		{"3.0 boolean exclusive bounds", schemaToOpenAPI30, `{"maximum": 10, "exclusiveMaximum": true}`,
+			`{"maximum": 10, "exclusiveMaximum": true}`},
+		{"3.0 identifiers", schemaToOpenAPI30, `{"$schema": "x", "$id": "y", "$comment": "z", "type": "object"}`,
+			`{"type": "object"}`},
+		{"3.1 nullable", schemaToOpenAPI31, `{"type": "string", "nullable": true}`, `{"type": ["string", "null"]}`},
+		{"3.1 not nullable", schemaToOpenAPI31, `{"type": "string", "nullable": false}`, `{"type": "string"}`},
+		{"3.1 exclusive minimum", schemaToOpenAPI31, `{"minimum": 0, "exclusiveMinimum": true}`, `{"exclusiveMinimum": 0}`},
+		{"3.1 inclusive maximum", schemaToOpenAPI31, `{"maximum": 10, "exclusiveMaximum": false}`, `{"maximum": 10}`},
+		{"3.1 numeric bounds", schemaToOpenAPI31, `{"exclusiveMinimum": 0}`, `{"exclusiveMinimum": 0}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := decodeJSONObject(t, tt.schema)
+			tt.convert(s)
+			if want := decodeJSONObject(t, tt.want); !reflect.DeepEqual(s, want) {
+				t.Fatalf("expected %v, got %v", want, s)
+			}
+		})
+	}
+}
+
+func TestNullableToJSONSchema(t *testing.T) {
+	tests := []struct {
+		name   string
+		schema string
+		want   string
+	}{
+		{"type", `{"type": "string"}`, `{"type": ["string", "null"]}`},
+		{"list of types", `{"type": ["string", "integer"]}`, `{"type": ["string", "integer", "null"]}`},
+		{"already null", `{"type": ["string", "null"]}`, `{"type": ["string", "null"]}`},
+		{"null type", `{"type": "null"}`, `{"type": "null"}`},
+		{"single allOf", `{"allOf": [{"$ref": "#/components/schemas/Pet"}]}`,
+			`{"anyOf": [{"$ref": "#/components/schemas/Pet"}, {"type": "null"}]}`},
+		{"anyOf", `{"anyOf": [{"type": "string"}, {"type": "integer"}]}`,
+			`{"anyOf": [{"type": "string"}, {"type": "integer"}, {"type": "null"}]}`},
+		{"oneOf", `{"oneOf": [{"type": "string"}]}`, `{"oneOf": [{"type": "string"}, {"type": "null"}]}`},
+		{"reference", `{"$ref": "#/components/schemas/Pet", "description": "The pet."}`,
+			`{"anyOf": [{"$ref": "#/components/schemas/Pet"}, {"type": "null"}], "description": "The pet."}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := decodeJSONObject(t, tt.schema)
+			nullableToJSONSchema(s)
+			if want := decodeJSONObject(t, tt.want); !reflect.DeepEqual(s, want) {
+				t.Fatalf("expected %v, got %v", want, s)
+			}
+		})
+	}
+}
+
+func TestHoistDefs(t *testing.T) {
+	schemas := decodeJSONObject(t, `{
+		"Pet": {
+			"type": "object",
+			"properties": {
+				"tag": {"$ref": "#/components/schemas/Pet/$defs/Tag"},
+				"owner": {"$ref": "#/components/schemas/Pet/definitions/Owner/properties/name"}
+			},
+			"$defs": {
+				"Tag": {"type": ["string", "null"], "$defs": {"Color": {"const": "red"}}},
+				"Other": {"$ref": "#/components/schemas/Pet/$defs/Tag"}
+			},
+			"definitions": {"Owner": {"type": "object", "properties": {"name": {"type": "string"}}}}
+		},
+		"Store": {"items": {"$ref": "#/components/schemas/Pet/$defs/Tag"}}
+	}`)
+	schemas["Pet"] = hoistDefs(schemas, "Pet", schemas["Pet"])
+
+	want := decodeJSONObject(t, `{
+		"Pet": {
+			"type": "object",
+			"properties": {
+				"tag": {"$ref": "#/components/schemas/Pet.Tag"},
+				"owner": {"$ref": "#/components/schemas/Pet.Owner/properties/name"}
+			}
+		},
+		"Pet.Tag": {"type": "string", "nullable": true},
+		"Pet.Tag.Color": {"enum": ["red"]},
+		"Pet.Other": {"$ref": "#/components/schemas/Pet.Tag"},
+		"Pet.Owner": {"type": "object", "properties": {"name": {"type": "string"}}},
+		"Store": {"items": {"$ref": "#/components/schemas/Pet.Tag"}}
+	}`)
+	if !reflect.DeepEqual(schemas, want) {
+		t.Fatalf("expected %v, got %v", want, schemas)
+	}
+}
+
+func TestOpenAPIVersions(t *testing.T) {
+	dir := t.TempDir()
+	pet := `{
+		"type": "object",
+		"properties": {"tag": {"$ref": "#/$defs/Tag"}, "age": {"type": "integer", "exclusiveMinimum": 0}},
+		"$defs": {"Tag": {"type": ["string", "null"]}}
+	}`
+	if err := os.WriteFile(filepath.Join(dir, "Pet.json"), []byte(pet), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		version OpenAPIVersion
+		want    map[string]string
+		missing []string
+	}{
+		{"3.0", OpenAPIVersion30, map[string]string{
+			"openapi":                          `"3.0.0"`,
+			"x-webhooks":                       `{"petAdded":{"post":{"operationId":"petAdded","responses":{}}}}`,
+			"components.schemas.Pet.tag":       `{"$ref":"#/components/schemas/Pet.Tag"}`,
+			"components.schemas.Pet.age":       `{"exclusiveMinimum":true,"minimum":0,"type":"integer"}`,
+			"components.schemas.Pet.Tag":       `{"nullable":true,"type":"string"}`,
+			"paths./pets.get.responses.200":    `{"$ref":"#/components/schemas/Pet"}`,
+			"paths./pets.get.parameters.limit": `{"nullable":true,"type":"integer"}`,
+		}, []string{"webhooks", "jsonSchemaDialect", "components.schemas.Pet.$defs"}},
+		{"3.1", OpenAPIVersion31, map[string]string{
+			"openapi":                          `"3.1.0"`,
+			"jsonSchemaDialect":                `"` + JSONSchemaDialect + `"`,
+			"webhooks":                         `{"petAdded":{"post":{"operationId":"petAdded","responses":{}}}}`,
+			"components.schemas.Pet.tag":       `{"$ref":"#/components/schemas/Pet/$defs/Tag"}`,
+			"components.schemas.Pet.age":       `{"exclusiveMinimum":0,"type":"integer"}`,
+			"components.schemas.Pet.$defs":     `{"Tag":{"type":["string","null"]}}`,
+			"paths./pets.get.responses.200":    `{"$ref":"#/components/schemas/Pet"}`,
+			"paths./pets.get.parameters.limit": `{"type":["integer","null"]}`,
+		}, []string{"x-webhooks", "components.schemas.Pet.Tag"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec, _, err := NewOpenAPI("Test", "", "http://localhost/api", "1.0.0", dir, nil, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			spec.SetOpenAPIVersion(tt.version)
+			spec.AddWebhook("petAdded", "POST", oasm.Operation{OperationId: "petAdded"})
+			spec.NewEndpoint("getPets", "GET", "/pets", "", "", nil).
+				Parameter(oasm.InQuery, "limit", "", false, map[string]interface{}{"type": []string{"integer", "null"}}, reflect.Int).
+				Response(200, "The pet.", Ref("{Pet}")).
+				MustDefine(func(Data) (interface{}, error) { return nil, nil })
+
+			b, err := spec.(*openAPI).docJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts := versionTestParts(decodeJSONObject(t, string(b)))
+			for key, want := range tt.want {
+				got, err := json.Marshal(parts[key])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(got) != want {
+					t.Fatalf("expected %s to be %s, got %s", key, want, got)
+				}
+			}
+			for _, key := range tt.missing {
+				if v, ok := parts[key]; ok && v != nil {
+					t.Fatalf("expected no %s, got %v", key, v)
+				}
+			}
+		})
+	}
+}
+
+// Get the parts of a marshaled spec which are checked by TestOpenAPIVersions, by their dotted paths.
+func versionTestParts(doc map[string]interface{}) map[string]interface{} {
+	schemas := mapOf(mapOf(doc["components"])["schemas"])
+	pet := mapOf(schemas["Pet"])
+	operation := mapOf(mapOf(doc["paths"])["/pets"])["get"]
+	response := mapOf(mapOf(mapOf(operation)["responses"])["200"])
+	params, _ := mapOf(operation)["parameters"].([]interface{})
+	var limit interface{}
+	if len(params) > 0 {
+		limit = mapOf(params[0])["schema"]
+	}
+	return map[string]interface{}{
+		"openapi":                          doc["openapi"],
+		"jsonSchemaDialect":                doc["jsonSchemaDialect"],
+		"webhooks":                         doc["webhooks"],
+		"x-webhooks":                       doc["x-webhooks"],
+		"components.schemas.Pet.tag":       mapOf(pet["properties"])["tag"],
+		"components.schemas.Pet.age":       mapOf(pet["properties"])["age"],
+		"components.schemas.Pet.$defs":     pet["$defs"],
+		"components.schemas.Pet.Tag":       schemas["Pet.Tag"],
+		"paths./pets.get.responses.200":    mapOf(mapOf(response["content"])[oasm.MimeJson])["schema"],
+		"paths./pets.get.parameters.limit": limit,
+	}
+}